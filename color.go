@@ -0,0 +1,339 @@
+package tint
+
+import (
+	"errors"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Color is a terminal color. The zero Color is the terminal's default color.
+type Color uint32
+
+const (
+	colorKindShift = 24
+	colorKindANSI  = 1 << colorKindShift
+	colorKindRGB   = 2 << colorKindShift
+)
+
+// ANSIColor returns the 8-bit [Color] with the given palette index:
+//
+//   - 0-7: standard ANSI colors
+//   - 8-15: high intensity ANSI colors
+//   - 16-231: 216 colors (6×6×6 cube)
+//   - 232-255: grayscale from dark to light in 24 steps
+//
+// See https://en.wikipedia.org/wiki/ANSI_escape_code#8-bit
+func ANSIColor(index uint8) Color {
+	return Color(colorKindANSI | uint32(index))
+}
+
+// RGB returns the 24-bit [Color] with the given red, green and blue components.
+// 24-bit colors are supported by most modern terminals.
+func RGB(r, g, b uint8) Color {
+	return Color(colorKindRGB | uint32(r)<<16 | uint32(g)<<8 | uint32(b))
+}
+
+var errInvalidHexColor = errors.New("tint: invalid hex color")
+
+// HexColor parses a 24-bit [Color] from its hex notation "#rrggbb" or "#rgb".
+// The leading "#" is optional.
+func HexColor(s string) (Color, error) {
+	if len(s) > 0 && s[0] == '#' {
+		s = s[1:]
+	}
+	if len(s) == 3 {
+		s = string([]byte{s[0], s[0], s[1], s[1], s[2], s[2]})
+	}
+	if len(s) != 6 {
+		return 0, errInvalidHexColor
+	}
+	rgb, err := strconv.ParseUint(s, 16, 32)
+	if err != nil {
+		return 0, errInvalidHexColor
+	}
+	return Color(colorKindRGB | uint32(rgb)), nil
+}
+
+// rgb returns the red, green and blue components of a 24-bit color.
+func (c Color) rgb() [3]uint8 {
+	return [3]uint8{uint8(c >> 16), uint8(c >> 8), uint8(c)}
+}
+
+// ansi returns the 8-bit palette index of an 8-bit color.
+func (c Color) ansi() uint8 {
+	return uint8(c)
+}
+
+// appendColor writes the SGR parameters that set the foreground color, or the
+// background color if background is set, to c.
+func appendColor(buf *buffer, c Color, background bool) {
+	var base uint64 = 30
+	if background {
+		base = 40
+	}
+
+	if c == 0 {
+		*buf = strconv.AppendUint(*buf, base+9, 10)
+		return
+	}
+	if c&colorKindRGB != 0 {
+		*buf = strconv.AppendUint(*buf, base+8, 10)
+		buf.WriteString(";2")
+		for _, v := range c.rgb() {
+			buf.WriteByte(';')
+			*buf = strconv.AppendUint(*buf, uint64(v), 10)
+		}
+		return
+	}
+
+	index := uint64(c.ansi())
+	switch {
+	case index < 8:
+		*buf = strconv.AppendUint(*buf, base+index, 10)
+	case index < 16:
+		*buf = strconv.AppendUint(*buf, base+60+index-8, 10)
+	default:
+		*buf = strconv.AppendUint(*buf, base+8, 10)
+		buf.WriteString(";5;")
+		*buf = strconv.AppendUint(*buf, index, 10)
+	}
+}
+
+// ColorMode controls whether colors are written.
+type ColorMode int
+
+const (
+	// ColorAlways always writes colors, unless Options.NoColor is set.
+	ColorAlways ColorMode = iota
+
+	// ColorAuto writes colors if the writer is a terminal. The conventions of
+	// the NO_COLOR, FORCE_COLOR, CLICOLOR and CLICOLOR_FORCE environment
+	// variables are honored, and colors are disabled if TERM is "dumb".
+	//
+	// See https://no-color.org, https://force-color.org and
+	// https://bixense.com/clicolors.
+	ColorAuto
+
+	// ColorNever never writes colors.
+	ColorNever
+)
+
+// colorEnabled reports whether colors should be written to w in the color
+// mode [ColorAuto].
+func colorEnabled(w io.Writer) bool {
+	if os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	if forceColor := os.Getenv("FORCE_COLOR"); forceColor != "" {
+		return forceColor != "0" && forceColor != "false"
+	}
+	if cliColorForce := os.Getenv("CLICOLOR_FORCE"); cliColorForce != "" && cliColorForce != "0" {
+		return true
+	}
+	if os.Getenv("CLICOLOR") == "0" || os.Getenv("TERM") == "dumb" {
+		return false
+	}
+	return isTerminal(w)
+}
+
+// isTerminal reports whether w is a character device, e.g. a terminal.
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return fi.Mode()&os.ModeCharDevice != 0
+}
+
+// ColorProfile is the set of colors supported by a terminal.
+type ColorProfile int
+
+const (
+	// ColorProfileAuto detects the color profile from the TERM and COLORTERM
+	// environment variables.
+	ColorProfileAuto ColorProfile = iota
+
+	// ColorProfileTrueColor supports 24-bit colors.
+	ColorProfileTrueColor
+
+	// ColorProfile256 supports 8-bit colors.
+	ColorProfile256
+
+	// ColorProfile16 supports the 16 standard and high intensity ANSI colors.
+	ColorProfile16
+
+	// ColorProfileNone supports no colors. Text attributes such as faint are
+	// still written.
+	ColorProfileNone
+)
+
+// detectColorProfile returns the color profile of the terminal based on the
+// TERM and COLORTERM environment variables.
+func detectColorProfile() ColorProfile {
+	switch colorTerm := strings.ToLower(os.Getenv("COLORTERM")); colorTerm {
+	case "truecolor", "24bit":
+		return ColorProfileTrueColor
+	}
+
+	term := strings.ToLower(os.Getenv("TERM"))
+	switch {
+	case term == "":
+		// unknown terminal, don't restrict colors
+		return ColorProfileTrueColor
+	case term == "dumb":
+		return ColorProfileNone
+	case strings.Contains(term, "truecolor") || strings.Contains(term, "24bit") || strings.Contains(term, "direct"):
+		return ColorProfileTrueColor
+	case strings.Contains(term, "256"):
+		return ColorProfile256
+	default:
+		return ColorProfile16
+	}
+}
+
+// convert returns the nearest color to c that is supported by the profile p.
+func (p ColorProfile) convert(c Color) Color {
+	if c == 0 {
+		return c
+	}
+
+	switch p {
+	case ColorProfileNone:
+		return 0
+	case ColorProfile256:
+		if c&colorKindRGB != 0 {
+			return ANSIColor(nearestANSI(c.rgb()))
+		}
+	case ColorProfile16:
+		if c&colorKindRGB != 0 {
+			return ANSIColor(nearestANSI16(c.rgb()))
+		} else if index := c.ansi(); index >= 16 {
+			return ANSIColor(nearestANSI16(ansiRGB(index)))
+		}
+	}
+	return c
+}
+
+// convertStyle returns s with all colors converted to the profile p.
+func (p ColorProfile) convertStyle(s Style) Style {
+	if p == ColorProfileTrueColor {
+		return s
+	}
+	s.Foreground = p.convert(s.Foreground)
+	s.Background = p.convert(s.Background)
+	return s
+}
+
+// convertTheme returns t with all styles converted to the profile p. It
+// returns t itself if no conversion is needed.
+func (p ColorProfile) convertTheme(t *Theme) *Theme {
+	if p == ColorProfileTrueColor {
+		return t
+	}
+	return &Theme{
+		Time:        p.convertStyle(t.Time),
+		Debug:       p.convertStyle(t.Debug),
+		Info:        p.convertStyle(t.Info),
+		Warn:        p.convertStyle(t.Warn),
+		Error:       p.convertStyle(t.Error),
+		Source:      p.convertStyle(t.Source),
+		Message:     p.convertStyle(t.Message),
+		Key:         p.convertStyle(t.Key),
+		Value:       p.convertStyle(t.Value),
+		Group:       p.convertStyle(t.Group),
+		Err:         p.convertStyle(t.Err),
+		Gutter:      p.convertStyle(t.Gutter),
+		JSONKey:     p.convertStyle(t.JSONKey),
+		JSONString:  p.convertStyle(t.JSONString),
+		JSONNumber:  p.convertStyle(t.JSONNumber),
+		JSONLiteral: p.convertStyle(t.JSONLiteral),
+	}
+}
+
+// ansi16 is the xterm default palette of the 16 standard and high intensity
+// ANSI colors.
+var ansi16 = [16][3]uint8{
+	{0x00, 0x00, 0x00}, {0xcd, 0x00, 0x00}, {0x00, 0xcd, 0x00}, {0xcd, 0xcd, 0x00},
+	{0x00, 0x00, 0xee}, {0xcd, 0x00, 0xcd}, {0x00, 0xcd, 0xcd}, {0xe5, 0xe5, 0xe5},
+	{0x7f, 0x7f, 0x7f}, {0xff, 0x00, 0x00}, {0x00, 0xff, 0x00}, {0xff, 0xff, 0x00},
+	{0x5c, 0x5c, 0xff}, {0xff, 0x00, 0xff}, {0x00, 0xff, 0xff}, {0xff, 0xff, 0xff},
+}
+
+// cubeLevels are the component values of the 6×6×6 color cube.
+var cubeLevels = [6]uint8{0x00, 0x5f, 0x87, 0xaf, 0xd7, 0xff}
+
+// ansiRGB returns the red, green and blue components of an 8-bit color.
+func ansiRGB(index uint8) [3]uint8 {
+	switch {
+	case index < 16:
+		return ansi16[index]
+	case index < 232:
+		index -= 16
+		return [3]uint8{cubeLevels[index/36], cubeLevels[index/6%6], cubeLevels[index%6]}
+	default:
+		gray := 8 + 10*(index-232)
+		return [3]uint8{gray, gray, gray}
+	}
+}
+
+// nearestANSI returns the index of the 8-bit color from the color cube or the
+// grayscale ramp that is nearest to the given color.
+func nearestANSI(c [3]uint8) uint8 {
+	cube := func(v uint8) uint8 {
+		switch {
+		case v < 0x30:
+			return 0
+		case v < 0x73:
+			return 1
+		default:
+			return (v - 0x23) / 0x28
+		}
+	}
+	cubeIndex := 16 + 36*cube(c[0]) + 6*cube(c[1]) + cube(c[2])
+
+	avg := (int(c[0]) + int(c[1]) + int(c[2])) / 3
+	var grayIndex uint8
+	if avg > 238 {
+		grayIndex = 255
+	} else if avg > 8 {
+		grayIndex = 232 + uint8((avg-3)/10)
+	} else {
+		grayIndex = 232
+	}
+
+	if colorDist(c, ansiRGB(grayIndex)) < colorDist(c, ansiRGB(cubeIndex)) {
+		return grayIndex
+	}
+	return cubeIndex
+}
+
+// nearestANSI16 returns the index of the standard or high intensity ANSI color
+// that is nearest to the given color.
+func nearestANSI16(c [3]uint8) uint8 {
+	var (
+		best     uint8
+		bestDist = -1
+	)
+	for i, c16 := range ansi16 {
+		if dist := colorDist(c, c16); bestDist < 0 || dist < bestDist {
+			best, bestDist = uint8(i), dist
+		}
+	}
+	return best
+}
+
+// colorDist returns the squared euclidean distance between two colors.
+func colorDist(c1, c2 [3]uint8) int {
+	var dist int
+	for i := range c1 {
+		d := int(c1[i]) - int(c2[i])
+		dist += d * d
+	}
+	return dist
+}
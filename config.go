@@ -0,0 +1,129 @@
+package tint
+
+import (
+	"io"
+	"log/slog"
+	"math"
+	"slices"
+	"sync"
+	"sync/atomic"
+)
+
+// config holds the current options of a handler and all handlers derived from
+// it. Options are never modified after they are stored, so handlers can tell
+// whether they were rendered with the current options by comparing pointers.
+type config struct {
+	mu   sync.Mutex // serializes updates
+	opts atomic.Pointer[Options]
+}
+
+// op is a WithAttrs or WithGroup call.
+type op struct {
+	attrs []slog.Attr
+	group string
+}
+
+// SetLevel sets the minimum level to log. If level is nil, slog.LevelInfo is
+// used. The level replaces the default level of Options.LevelSpec, if any,
+// while the levels of its modules are kept.
+func (h *Handler) SetLevel(level slog.Leveler) {
+	if level == nil {
+		level = defaultLevel
+	}
+	h.update(func(o *Options) {
+		o.Level = level
+		o.LevelSpec = slices.DeleteFunc(slices.Clone(o.LevelSpec), func(m ModuleLevel) bool {
+			return m.Module == ""
+		})
+	})
+}
+
+// SetColorMode sets the color mode. It overrides Options.NoColor, so that
+// ColorAlways enables colors.
+func (h *Handler) SetColorMode(mode ColorMode) {
+	h.update(func(o *Options) {
+		o.ColorMode, o.NoColor = mode, false
+		o.applyColorMode(h.w)
+	})
+}
+
+// SetTimeFormat sets the time format. If format is empty, time.StampMilli is
+// used.
+func (h *Handler) SetTimeFormat(format string) {
+	if format == "" {
+		format = defaultTimeFormat
+	}
+	h.update(func(o *Options) { o.TimeFormat = format })
+}
+
+// SetTheme sets the theme. If theme is nil, DarkTheme() is used.
+func (h *Handler) SetTheme(theme *Theme) {
+	if theme == nil {
+		theme = DarkTheme()
+	}
+	h.update(func(o *Options) { o.Theme = theme })
+}
+
+// update stores a copy of the current options modified by f.
+func (h *Handler) update(f func(o *Options)) {
+	if h.config == nil {
+		return // zero Handler
+	}
+	h.config.mu.Lock()
+	defer h.config.mu.Unlock()
+
+	opts := *h.config.opts.Load()
+	f(&opts)
+	h.config.opts.Store(&opts)
+}
+
+// discardHandler is the handler used in place of the zero Handler, which
+// discards all records.
+var discardHandler = sync.OnceValue(func() *Handler {
+	return NewHandler(io.Discard, &Options{Level: slog.Level(math.MaxInt)}).(*Handler)
+})
+
+// current returns h, or h rendered with the current options if they have been
+// updated since h was created.
+func (h *Handler) current() *Handler {
+	if h.config == nil {
+		return discardHandler()
+	}
+	opts := h.config.opts.Load()
+	if opts == h.opts {
+		return h
+	}
+	if h2 := h.rendered.Load(); h2 != nil && h2.opts == opts {
+		return h2
+	}
+	h2 := h.render(opts)
+	h.rendered.Store(h2)
+	return h2
+}
+
+// render returns a handler with the given options that shares its state with
+// h, and replays the WithAttrs and WithGroup calls h was derived with.
+func (h *Handler) render(opts *Options) *Handler {
+	h2 := &Handler{
+		mu:     h.mu,
+		w:      h.w,
+		opts:   opts,
+		theme:  opts.ColorProfile.convertTheme(opts.Theme),
+		levels: mergeLevels(opts.LevelFormat, opts.Levels),
+		widths: h.widths,
+		clock:  h.clock,
+		config: h.config,
+		format: newFormatHandler(h.w, h.mu, opts),
+	}
+	for i, l := range h2.levels {
+		h2.levels[i].Style = opts.ColorProfile.convertStyle(l.Style)
+	}
+	for _, op := range h.ops {
+		if op.group != "" {
+			h2 = h2.withGroup(op.group)
+		} else {
+			h2 = h2.withAttrs(op.attrs)
+		}
+	}
+	return h2
+}
@@ -0,0 +1,95 @@
+package tint
+
+import (
+	"reflect"
+	"strings"
+)
+
+// maxErrorDepth is the maximum depth of the error tree written by
+// [Handler.appendErrorBlock].
+const maxErrorDepth = 32
+
+// unwrapErr returns the errors wrapped by err, using either an Unwrap() error
+// or an Unwrap() []error method. It returns nil if err is a nil pointer.
+func unwrapErr(err error) []error {
+	if v := reflect.ValueOf(err); v.Kind() == reflect.Pointer && v.IsNil() {
+		return nil
+	}
+
+	switch u := err.(type) {
+	case interface{ Unwrap() error }:
+		if err := u.Unwrap(); err != nil {
+			return []error{err}
+		}
+	case interface{ Unwrap() []error }:
+		errs := make([]error, 0, len(u.Unwrap()))
+		for _, err := range u.Unwrap() {
+			if err != nil {
+				errs = append(errs, err)
+			}
+		}
+		return errs
+	}
+	return nil
+}
+
+// errorMessage returns the message of err, or "<nil>" if err is a nil pointer,
+// like [Handler.appendValue].
+func errorMessage(err error) string {
+	if v := reflect.ValueOf(err); v.Kind() == reflect.Pointer && v.IsNil() {
+		return "<nil>"
+	}
+	return err.Error()
+}
+
+// appendErrorBlock writes the tree of errors wrapped by err as a block, with
+// one error per line. Each line holds the part of the error message that is
+// not repeated by the wrapped errors, followed by the concrete type of the
+// error.
+func (h *Handler) appendErrorBlock(buf *buffer, key, groupsPrefix string, err error, keyStyle, valStyle Style) {
+	h.appendBlockKey(buf, key, groupsPrefix, keyStyle)
+	h.appendErrorTree(buf, err, 0, keyStyle, valStyle)
+}
+
+func (h *Handler) appendErrorTree(buf *buffer, err error, depth int, typeStyle, msgStyle Style) {
+	errs := unwrapErr(err)
+	msg := errorMessage(err)
+	switch {
+	case len(errs) == 1:
+		// e.g. fmt.Errorf("context: %w", err)
+		if prefix, ok := strings.CutSuffix(msg, errorMessage(errs[0])); ok {
+			msg = strings.TrimRight(prefix, ": ")
+		}
+	case len(errs) > 1:
+		// e.g. errors.Join(errs...)
+		msgs := make([]string, len(errs))
+		for i, err := range errs {
+			msgs[i] = errorMessage(err)
+		}
+		if msg == strings.Join(msgs, "\n") {
+			msg = ""
+		}
+	}
+
+	h.appendGutter(buf)
+	appendPadding(buf, 2*depth)
+	if msg != "" {
+		h.appendStyle(buf, Style{}, msgStyle)
+		buf.WriteString(strings.ReplaceAll(msg, "\n", " "))
+		h.appendStyle(buf, msgStyle, Style{})
+		buf.WriteByte(' ')
+	}
+	h.appendStyle(buf, Style{}, typeStyle)
+	buf.WriteByte('(')
+	buf.WriteString(reflect.TypeOf(err).String())
+	buf.WriteByte(')')
+	h.appendStyle(buf, typeStyle, Style{})
+	buf.WriteByte('\n')
+
+	if depth+1 >= maxErrorDepth {
+		return
+	}
+	for _, err := range errs {
+		h.appendErrorTree(buf, err, depth+1, typeStyle, msgStyle)
+	}
+}
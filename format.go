@@ -0,0 +1,110 @@
+package tint
+
+import (
+	"io"
+	"log/slog"
+	"math"
+	"sync"
+)
+
+// Format is the output format of a handler.
+type Format int
+
+// The options that only affect the layout of the tinted text, e.g. colors,
+// themes, column widths and blocks below the line, are ignored by FormatLogfmt
+// and FormatJSON, as is Options.TimeMode. FormatJSON writes times in RFC 3339
+// and durations in nanoseconds like slog.JSONHandler, ignoring
+// Options.TimeFormat, Options.AttrTimeFormat and Options.DurationFormat.
+const (
+	FormatText   Format = iota // tinted text
+	FormatLogfmt               // plain logfmt, as written by slog.TextHandler
+	FormatJSON                 // JSON, as written by slog.JSONHandler
+)
+
+// newFormatHandler returns the [slog.Handler] that writes records in
+// Options.Format, or nil for FormatText. Tinted attributes are written as
+// their plain values. Options.AddSource, Options.SourceSkip,
+// Options.ReplaceAttr, Options.TimeLocation, Options.Levels and
+// Options.LevelFormat apply to all formats, so levels are labeled the same
+// way, e.g. "INF" or "TRC+1".
+// Options.TimeFormat, Options.AttrTimeFormat and Options.DurationFormat only
+// apply to FormatLogfmt.
+func newFormatHandler(w io.Writer, mu *sync.Mutex, opts *Options) slog.Handler {
+	if opts.Format == FormatText {
+		return nil
+	}
+
+	levels := mergeLevels(opts.LevelFormat, opts.Levels)
+	logfmt := opts.Format == FormatLogfmt
+	handlerOpts := &slog.HandlerOptions{
+		AddSource: opts.AddSource,
+		Level:     slog.Level(math.MinInt), // levels are checked by the Handler
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			// like in FormatText, ReplaceAttr is called with the original values
+			if opts.ReplaceAttr != nil {
+				a = opts.ReplaceAttr(groups, a)
+				a.Value = a.Value.Resolve()
+			}
+			builtin := len(groups) == 0
+
+			switch a.Value.Kind() {
+			case slog.KindTime:
+				t := a.Value.Time()
+				if opts.TimeLocation != nil {
+					t = t.In(opts.TimeLocation)
+				}
+				switch {
+				case logfmt && builtin && a.Key == slog.TimeKey:
+					a.Value = slog.StringValue(t.Format(opts.TimeFormat))
+				case logfmt && opts.AttrTimeFormat != "":
+					a.Value = slog.StringValue(t.Format(opts.AttrTimeFormat))
+				default:
+					a.Value = slog.TimeValue(t)
+				}
+			case slog.KindDuration:
+				if logfmt && opts.DurationFormat != DurationFormatString {
+					buf := newBuffer()
+					defer buf.Free()
+
+					appendDurationFormat(buf, a.Value.Duration(), opts.DurationFormat)
+					a.Value = slog.StringValue(string(*buf))
+				}
+			case slog.KindAny:
+				if level, ok := a.Value.Any().(slog.Level); ok && builtin && a.Key == slog.LevelKey {
+					if l := findLevel(levels, level); l.Level == level {
+						a.Value = slog.StringValue(l.Label)
+					} else {
+						a.Value = slog.StringValue(string(appendLevelLabel(nil, l, level)))
+					}
+				}
+			}
+			return a
+		},
+	}
+
+	// clones of the handler created by Handler.render must not interleave
+	// their writes
+	w = &lockedWriter{mu: mu, w: w}
+
+	switch opts.Format {
+	case FormatLogfmt:
+		return slog.NewTextHandler(w, handlerOpts)
+	case FormatJSON:
+		return slog.NewJSONHandler(w, handlerOpts)
+	default:
+		return nil
+	}
+}
+
+// lockedWriter is an [io.Writer] that holds the mutex of a handler while
+// writing.
+type lockedWriter struct {
+	mu *sync.Mutex
+	w  io.Writer
+}
+
+func (w *lockedWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.w.Write(p)
+}
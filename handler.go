@@ -12,24 +12,6 @@ Options.ReplaceAttr can be used to alter or drop attributes. If set, it is
 called on each non-group attribute before it is logged.
 See [slog.HandlerOptions] for details.
 
-Create a new logger with a custom TRACE level:
-
-	const LevelTrace = slog.LevelDebug - 4
-
-	w := os.Stderr
-	logger := slog.New(tint.NewHandler(w, &tint.Options{
-		Level: LevelTrace,
-		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
-			if a.Key == slog.LevelKey && len(groups) == 0 {
-				level, ok := a.Value.Any().(slog.Level)
-				if ok && level <= LevelTrace {
-					return tint.Attr(13, slog.String(a.Key, "TRC"))
-				}
-			}
-			return a
-		},
-	}))
-
 Create a new logger that doesn't write the time:
 
 	w := os.Stderr
@@ -60,16 +42,51 @@ Create a new logger that writes all errors in red:
 		}),
 	)
 
+# Custom Levels
+
+Options.Levels can be used to add custom levels or to change the label and style
+of the built-in levels.
+
+Create a new logger with a custom TRACE level:
+
+	const LevelTrace = slog.LevelDebug - 4
+
+	w := os.Stderr
+	logger := slog.New(tint.NewHandler(w, &tint.Options{
+		Level: LevelTrace,
+		Levels: []tint.Level{
+			{Level: LevelTrace, Label: "TRC", Style: tint.Style{Foreground: tint.ANSIColor(13)}},
+		},
+	}))
+
+# Themes
+
+The styles used to write each part of a log record are defined by a [Theme].
+The default theme [DarkTheme] is designed for terminals with a dark background.
+Use [LightTheme] for terminals with a light background or define a custom theme:
+
+	w := os.Stderr
+	logger := slog.New(
+		tint.NewHandler(w, &tint.Options{
+			Theme: tint.LightTheme(),
+		}),
+	)
+
+Colors that are not supported by the terminal are downsampled to the nearest
+supported color. The supported colors are detected from the TERM and COLORTERM
+environment variables or can be set using the Options.ColorProfile field.
+
 # Automatically Enable Colors
 
 Colors are enabled by default. Use the Options.NoColor field to disable
-color output. To automatically enable colors based on terminal capabilities, use
-e.g., the [go-isatty] package:
+color output. To automatically enable colors only if the writer is a terminal,
+use the ColorAuto mode, which also honors the NO_COLOR, FORCE_COLOR, CLICOLOR
+and CLICOLOR_FORCE environment variables:
 
 	w := os.Stderr
 	logger := slog.New(
 		tint.NewHandler(w, &tint.Options{
-			NoColor: !isatty.IsTerminal(w.Fd()),
+			ColorMode: tint.ColorAuto,
 		}),
 	)
 
@@ -83,23 +100,25 @@ Color support on Windows can be added by using e.g., the [go-colorable] package:
 	)
 
 [zerolog.ConsoleWriter]: https://pkg.go.dev/github.com/rs/zerolog#ConsoleWriter
-[go-isatty]: https://pkg.go.dev/github.com/mattn/go-isatty
 [go-colorable]: https://pkg.go.dev/github.com/mattn/go-colorable
 */
 package tint
 
 import (
+	"cmp"
 	"context"
 	"encoding"
+	"encoding/json"
 	"fmt"
 	"io"
 	"log/slog"
-	"path/filepath"
+	"os"
 	"reflect"
-	"runtime"
+	"slices"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 	"unicode"
 	"unicode/utf8"
@@ -107,20 +126,30 @@ import (
 
 const (
 	// ANSI modes
-	ansiEsc          = '\u001b'
-	ansiReset        = "\u001b[0m"
-	ansiFaint        = "\u001b[2m"
-	ansiResetFaint   = "\u001b[22m"
-	ansiBrightRed    = "\u001b[91m"
-	ansiBrightGreen  = "\u001b[92m"
-	ansiBrightYellow = "\u001b[93m"
+	ansiEsc   = '\u001b'
+	ansiReset = "\u001b[0m"
+
+	// OSC 8 hyperlinks
+	ansiLinkStart = "\u001b]8;;"
+	ansiLinkEnd   = "\u001b\\"
 
 	errKey = "err"
 
-	defaultLevel      = slog.LevelInfo
-	defaultTimeFormat = time.StampMilli
+	defaultLevel          = slog.LevelInfo
+	defaultTimeFormat     = time.StampMilli
+	defaultMaxValueDepth  = 4
+	defaultMaxValueLength = 16
 )
 
+// AdaptiveWidth can be used as Options.LevelWidth, Options.SourceWidth or
+// Options.MessageWidth to pad a column to the widest value written so far by
+// the handler and all handlers derived from it.
+const AdaptiveWidth = -1
+
+// TerminalWidth can be used as Options.LineWidth to wrap lines at the width of
+// the terminal, as given by the COLUMNS environment variable.
+const TerminalWidth = -1
+
 // Options for a slog.Handler that writes tinted logs. A zero Options consists
 // entirely of default values.
 //
@@ -132,6 +161,18 @@ type Options struct {
 	// Minimum level to log (Default: slog.LevelInfo)
 	Level slog.Leveler
 
+	// Minimum levels of modules, which are matched against the groups of the
+	// handler and the package of the caller. The default level of the spec
+	// takes precedence over Options.Level, and is replaced by
+	// [Handler.SetLevel]. Use [ParseLevelSpec] to parse a spec like
+	// "info,db=debug,http.client=warn". (Default: nil)
+	LevelSpec LevelSpec
+
+	// Output format. Use FormatJSON or FormatLogfmt to write machine-readable
+	// logs with the same options, e.g. in production. Options that only affect
+	// the tinted text are ignored, see [Format]. (Default: FormatText)
+	Format Format
+
 	// ReplaceAttr is called to rewrite each non-group attribute before it is logged.
 	// See https://pkg.go.dev/log/slog#HandlerOptions for details.
 	ReplaceAttr func(groups []string, attr slog.Attr) slog.Attr
@@ -139,8 +180,134 @@ type Options struct {
 	// Time format (Default: time.StampMilli)
 	TimeFormat string
 
+	// Time mode, e.g. TimeModeElapsed to write the time elapsed since the
+	// handler was created instead of the wall clock. (Default: TimeModeWall)
+	TimeMode TimeMode
+
+	// Location of the time of records and of time attributes, e.g. time.UTC.
+	// If nil, times are written in their own location. (Default: nil)
+	TimeLocation *time.Location
+
+	// Time format of time attributes (Default: RFC 3339 with milliseconds)
+	AttrTimeFormat string
+
+	// Format of duration attributes (Default: DurationFormatString)
+	DurationFormat DurationFormat
+
+	// Styles of durations at or above a threshold, e.g. yellow above 500ms
+	// and red above 2s. Each style applies to all durations from its threshold
+	// up to the next threshold. Styles of tinted attributes take precedence.
+	// (Default: nil)
+	DurationStyles []DurationStyle
+
 	// Disable color (Default: false)
 	NoColor bool
+
+	// Color mode (Default: ColorAlways). Use ColorAuto to write colors only if
+	// the writer is a terminal. Options.NoColor takes precedence.
+	ColorMode ColorMode
+
+	// Theme used to style the output (Default: DarkTheme())
+	Theme *Theme
+
+	// Custom levels, e.g. TRACE or FATAL. Each level applies to all levels
+	// from its threshold up to the next threshold. Custom levels take
+	// precedence over the built-in levels DBG, INF, WRN and ERR. (Default: nil)
+	Levels []Level
+
+	// Format of the built-in level labels (Default: LevelFormatShort)
+	LevelFormat LevelFormat
+
+	// Minimum width of the level. Shorter levels are padded with spaces, so
+	// that messages start in the same column. Use AdaptiveWidth to pad to the
+	// widest level written so far. (Default: 0)
+	LevelWidth int
+
+	// Skip frames of functions with one of these prefixes when determining the
+	// source, e.g. "github.com/acme/log." for all functions of a package that
+	// wraps slog. The source is the first frame that is not skipped.
+	// (Default: nil)
+	SourceSkip []string
+
+	// Format of the source (Default: SourceFormatShort)
+	SourceFormat SourceFormat
+
+	// Prefix that is trimmed from source file paths written with
+	// SourceFormatRelative. If empty, paths are written relative to the root
+	// of the module of the source. (Default: "")
+	SourceRoot string
+
+	// URL template of OSC 8 hyperlinks written around the source, which make
+	// it clickable in terminals that support them. The placeholders {path} and
+	// {line} are replaced with the absolute file path and the line of the
+	// source, e.g. "vscode://file/{path}:{line}". Use SourceLinkFile to link
+	// to the file. Hyperlinks are not written if colors are disabled.
+	// (Default: "", no hyperlinks)
+	SourceLink string
+
+	// Write the name of the function after the source, e.g.
+	// "dir/file.go:42 pkg.Func". (Default: false)
+	SourceFunction bool
+
+	// Minimum width of the source. Shorter sources are padded with spaces, so
+	// that messages start in the same column. Use AdaptiveWidth to pad to the
+	// widest source written so far. (Default: 0)
+	SourceWidth int
+
+	// Minimum width of the message. Shorter messages are padded with spaces, so
+	// that attributes start in the same column. Use AdaptiveWidth to pad to the
+	// widest message written so far. (Default: 0)
+	MessageWidth int
+
+	// Maximum width of a line. Longer lines are wrapped between attributes and
+	// continued below the message. Use TerminalWidth to wrap at the width of
+	// the terminal, as given by the COLUMNS environment variable.
+	// (Default: 0, no wrapping)
+	LineWidth int
+
+	// Write string values that contain newlines as indented blocks below the
+	// line, instead of quoting them. (Default: false)
+	MultilineValues bool
+
+	// Write structs, maps, slices and arrays in a compact, colorized form, e.g.
+	// {Name:"Jane Doe" Tags:[a b]}, instead of formatting them with "%+v".
+	// (Default: false)
+	PrettyValues bool
+
+	// Maximum nesting depth of values written with PrettyValues (Default: 4)
+	MaxValueDepth int
+
+	// Maximum number of fields or elements of a struct, map, slice or array
+	// written with PrettyValues (Default: 16)
+	MaxValueLength int
+
+	// Write values that implement [json.Marshaler] as compact JSON with syntax
+	// highlighting. Values that implement [encoding.TextMarshaler] are not
+	// affected. (Default: false)
+	JSONValues bool
+
+	// Write errors that wrap other errors as indented trees below the line,
+	// with one cause per line and its concrete type. (Default: false)
+	ExpandErrors bool
+
+	// Write a stack trace below records at or above this level, and below
+	// records with an error attribute created with [Err]. The stack trace of
+	// the error is used if it provides one, e.g. with a StackTrace() method,
+	// otherwise the stack of the log call. (Default: nil, no stack traces)
+	StackTraceLevel slog.Leveler
+
+	// Write only stack frames of functions with one of these prefixes, e.g.
+	// the path of the main module. Frames of the standard library are styled
+	// like the source. (Default: nil, all frames)
+	StackTracePrefixes []string
+
+	// Write levels as badges, e.g. " ERR " with a red background. Level styles
+	// without a background color are inverted. (Default: false)
+	LevelBadge bool
+
+	// Colors supported by the terminal. Colors that are not supported are
+	// downsampled to the nearest supported color. (Default: ColorProfileAuto)
+	ColorProfile ColorProfile
 }
 
 func (o *Options) setDefaults() {
@@ -150,51 +317,232 @@ func (o *Options) setDefaults() {
 	if o.TimeFormat == "" {
 		o.TimeFormat = defaultTimeFormat
 	}
+	if o.MaxValueDepth <= 0 {
+		o.MaxValueDepth = defaultMaxValueDepth
+	}
+	if o.MaxValueLength <= 0 {
+		o.MaxValueLength = defaultMaxValueLength
+	}
+	if o.Theme == nil {
+		o.Theme = DarkTheme()
+	}
+	if o.ColorProfile == ColorProfileAuto {
+		o.ColorProfile = detectColorProfile()
+	}
+}
+
+// Level defines the label and style of a level. A log record is written with
+// the Level with the highest threshold that is less than or equal to the level
+// of the record. If the record level differs from the threshold, the
+// difference is appended to the label, e.g. "INF+2".
+type Level struct {
+	// Minimum level
+	Level slog.Level
+
+	// Label of the level, e.g. "TRC"
+	Label string
+
+	// Style of the level. If zero, the theme style of the level is used.
+	Style Style
+}
+
+// LevelFormat is the format of the built-in level labels. Labels of custom
+// levels in Options.Levels are written as is.
+type LevelFormat int
+
+const (
+	LevelFormatShort  LevelFormat = iota // DBG, INF, WRN, ERR
+	LevelFormatLong                      // DEBUG, INFO, WARN, ERROR
+	LevelFormatLetter                    // D, I, W, E
+)
+
+// defaultLevels are the built-in levels of each LevelFormat.
+var defaultLevels = [...][]Level{
+	LevelFormatShort: {
+		{Level: slog.LevelDebug, Label: "DBG"},
+		{Level: slog.LevelInfo, Label: "INF"},
+		{Level: slog.LevelWarn, Label: "WRN"},
+		{Level: slog.LevelError, Label: "ERR"},
+	},
+	LevelFormatLong: {
+		{Level: slog.LevelDebug, Label: "DEBUG"},
+		{Level: slog.LevelInfo, Label: "INFO"},
+		{Level: slog.LevelWarn, Label: "WARN"},
+		{Level: slog.LevelError, Label: "ERROR"},
+	},
+	LevelFormatLetter: {
+		{Level: slog.LevelDebug, Label: "D"},
+		{Level: slog.LevelInfo, Label: "I"},
+		{Level: slog.LevelWarn, Label: "W"},
+		{Level: slog.LevelError, Label: "E"},
+	},
+}
+
+// mergeLevels returns the built-in levels of the given format merged with the
+// custom levels, sorted by threshold.
+func mergeLevels(format LevelFormat, custom []Level) []Level {
+	if format < 0 || int(format) >= len(defaultLevels) {
+		format = LevelFormatShort
+	}
+	builtin := defaultLevels[format]
+	if len(custom) == 0 {
+		return builtin
+	}
+
+	levels := make([]Level, 0, len(builtin)+len(custom))
+	levels = append(levels, custom...)
+	for _, l := range builtin {
+		if !slices.ContainsFunc(custom, func(c Level) bool { return c.Level == l.Level }) {
+			levels = append(levels, l)
+		}
+	}
+	slices.SortStableFunc(levels, func(a, b Level) int { return cmp.Compare(a.Level, b.Level) })
+	return levels
 }
 
 // NewHandler creates a [slog.Handler] that writes tinted logs to Writer w,
 // using the default options. If opts is nil, the default options are used.
+// The returned handler is a [*Handler], which can be reconfigured at runtime.
 func NewHandler(w io.Writer, opts *Options) slog.Handler {
 	if opts == nil {
 		opts = &Options{}
 	}
 	opts.setDefaults()
 
-	return &handler{
-		mu:   &sync.Mutex{},
-		w:    w,
-		opts: *opts,
+	o := *opts
+	o.applyColorMode(w)
+	if o.LineWidth == TerminalWidth {
+		o.LineWidth, _ = strconv.Atoi(os.Getenv("COLUMNS"))
+	}
+
+	h := &Handler{
+		mu:     &sync.Mutex{},
+		w:      w,
+		widths: &columnWidths{},
+		clock:  newClock(),
+		config: &config{},
 	}
+	h.config.opts.Store(&o)
+	return h.render(&o)
 }
 
-// handler implements a [slog.Handler].
-type handler struct {
-	attrsPrefix string
-	groupPrefix string
-	groups      []string
+// applyColorMode disables colors according to ColorMode and the writer w.
+func (o *Options) applyColorMode(w io.Writer) {
+	switch o.ColorMode {
+	case ColorAuto:
+		o.NoColor = o.NoColor || !colorEnabled(w)
+	case ColorNever:
+		o.NoColor = true
+	}
+}
+
+// Handler implements a [slog.Handler] that writes tinted logs.
+//
+// The level, color mode, time format and theme of a Handler can be changed
+// at runtime. Changes apply to the handler and all handlers derived from it
+// with WithAttrs and WithGroup, and to the handler it was derived from.
+//
+// A Handler must be created with [NewHandler]. The zero Handler discards all
+// records.
+type Handler struct {
+	attrsPrefix     string
+	attrsPrefixEnds []int  // offsets in attrsPrefix after each attribute
+	attrsBlocks     string // blocks of multi-line attributes in attrsPrefix
+	attrsErr        error  // first error attribute in attrsPrefix
+	attrsStack      bool   // attrsPrefix contains an error attribute created with Err
+	groupPrefix     string
+	groups          []string
+	ops             []op // WithAttrs and WithGroup calls this handler was derived with
 
 	mu *sync.Mutex
 	w  io.Writer
 
-	opts Options
+	opts   *Options      // options this handler was rendered with
+	theme  *Theme        // Options.Theme converted to Options.ColorProfile
+	levels []Level       // sorted by threshold, styles converted to Options.ColorProfile
+	widths *columnWidths // adaptive column widths
+	clock  *clock        // start time and time of the previous record
+	config *config       // current options
+	format slog.Handler  // handler of Options.Format, or nil for FormatText
+
+	rendered atomic.Pointer[Handler] // handler rendered with the current options
+}
+
+// layout collects the positions of attributes in the buffer while writing a
+// record, which are needed to wrap lines, and the blocks that are written below
+// the line.
+type layout struct {
+	ends   []int  // offsets in the buffer after each attribute
+	blocks buffer // blocks written below the line
+	err    error  // first error attribute, which may provide a stack trace
+	stack  bool   // an error attribute was created with Err
+}
+
+// columnWidths are the widest level, source and message written so far.
+type columnWidths struct {
+	level, source, message atomic.Int64
 }
 
-func (h *handler) clone() *handler {
-	return &handler{
-		attrsPrefix: h.attrsPrefix,
-		groupPrefix: h.groupPrefix,
-		groups:      h.groups,
-		mu:          h.mu, // mutex shared among all clones of this handler
-		w:           h.w,
-		opts:        h.opts,
+// fit returns the width a value of width n should be padded to. If width is
+// AdaptiveWidth, n is recorded in maxWidth.
+func fit(width, n int, maxWidth *atomic.Int64) int {
+	if width != AdaptiveWidth {
+		return width
+	}
+	for {
+		cur := maxWidth.Load()
+		if int64(n) <= cur {
+			return int(cur)
+		}
+		if maxWidth.CompareAndSwap(cur, int64(n)) {
+			return n
+		}
 	}
 }
 
-func (h *handler) Enabled(_ context.Context, level slog.Level) bool {
+func (h *Handler) clone() *Handler {
+	return &Handler{
+		attrsPrefix:     h.attrsPrefix,
+		attrsPrefixEnds: h.attrsPrefixEnds,
+		attrsBlocks:     h.attrsBlocks,
+		attrsErr:        h.attrsErr,
+		attrsStack:      h.attrsStack,
+		groupPrefix:     h.groupPrefix,
+		groups:          h.groups,
+		ops:             h.ops,
+		mu:              h.mu, // mutex shared among all clones of this handler
+		w:               h.w,
+		opts:            h.opts,
+		theme:           h.theme,
+		levels:          h.levels,
+		widths:          h.widths, // column widths shared among all clones of this handler
+		clock:           h.clock,  // clock shared among all clones of this handler
+		config:          h.config, // options shared among all clones of this handler
+		format:          h.format,
+	}
+}
+
+func (h *Handler) Enabled(_ context.Context, level slog.Level) bool {
+	h = h.current()
+	if len(h.opts.LevelSpec) > 0 {
+		return level >= h.minLevel()
+	}
 	return level >= h.opts.Level.Level()
 }
 
-func (h *handler) Handle(_ context.Context, r slog.Record) error {
+func (h *Handler) Handle(ctx context.Context, r slog.Record) error {
+	h = h.current()
+	if len(h.opts.LevelSpec) > 0 && r.Level < h.levelFor(r.PC) {
+		return nil
+	}
+	if h.format != nil {
+		if h.opts.AddSource && len(h.opts.SourceSkip) > 0 && r.PC != 0 {
+			// the format handler writes the source of r.PC, a return address
+			r.PC = h.callerFrame(r.PC).PC + 1
+		}
+		return h.format.Handle(ctx, r)
+	}
+
 	// get a buffer from the sync pool
 	buf := newBuffer()
 	defer buf.Free()
@@ -204,16 +552,16 @@ func (h *handler) Handle(_ context.Context, r slog.Record) error {
 	// write time
 	if !r.Time.IsZero() {
 		if rep == nil {
-			h.appendTintTime(buf, r.Time, -1)
+			h.appendTintTime(buf, r.Time, Style{})
 			buf.WriteByte(' ')
 		} else {
 			val := r.Time.Round(0) // strip monotonic to match Attr behavior
 			if a := rep(nil /* groups */, slog.Time(slog.TimeKey, val)); a.Key != "" {
-				val, color := h.resolve(a.Value)
+				val, style := h.resolve(a.Value)
 				if val.Kind() == slog.KindTime {
-					h.appendTintTime(buf, val.Time(), color)
+					h.appendTintTime(buf, val.Time(), style)
 				} else {
-					h.appendTintValue(buf, val, false, color, true)
+					h.appendTintValue(buf, val, false, h.theme.Time.with(style))
 				}
 				buf.WriteByte(' ')
 			}
@@ -222,26 +570,25 @@ func (h *handler) Handle(_ context.Context, r slog.Record) error {
 
 	// write level
 	if rep == nil {
-		h.appendTintLevel(buf, r.Level, -1)
+		h.appendTintLevel(buf, r.Level, Style{})
 		buf.WriteByte(' ')
 	} else if a := rep(nil /* groups */, slog.Any(slog.LevelKey, r.Level)); a.Key != "" {
-		val, color := h.resolve(a.Value)
+		val, style := h.resolve(a.Value)
 		if val.Kind() == slog.KindAny {
 			if lvlVal, ok := val.Any().(slog.Level); ok {
-				h.appendTintLevel(buf, lvlVal, color)
+				h.appendTintLevel(buf, lvlVal, style)
 			} else {
-				h.appendTintValue(buf, val, false, color, false)
+				h.appendTintValue(buf, val, false, style)
 			}
 		} else {
-			h.appendTintValue(buf, val, false, color, false)
+			h.appendTintValue(buf, val, false, style)
 		}
 		buf.WriteByte(' ')
 	}
 
 	// write source
 	if h.opts.AddSource {
-		fs := runtime.CallersFrames([]uintptr{r.PC})
-		f, _ := fs.Next()
+		f := h.callerFrame(r.PC)
 		if f.File != "" {
 			src := &slog.Source{
 				Function: f.Function,
@@ -249,50 +596,101 @@ func (h *handler) Handle(_ context.Context, r slog.Record) error {
 				Line:     f.Line,
 			}
 
+			start := len(*buf)
 			if rep == nil {
-				if h.opts.NoColor {
-					appendSource(buf, src)
-				} else {
-					buf.WriteString(ansiFaint)
-					appendSource(buf, src)
-					buf.WriteString(ansiReset)
-				}
+				h.appendStyle(buf, Style{}, h.theme.Source)
+				h.appendSource(buf, src)
+				h.appendStyle(buf, h.theme.Source, Style{})
+				h.appendColumnPadding(buf, start, h.opts.SourceWidth, &h.widths.source)
 				buf.WriteByte(' ')
 			} else if a := rep(nil /* groups */, slog.Any(slog.SourceKey, src)); a.Key != "" {
-				val, color := h.resolve(a.Value)
-				h.appendTintValue(buf, val, false, color, true)
+				val, style := h.resolve(a.Value)
+				h.appendTintValue(buf, val, false, h.theme.Source.with(style))
+				h.appendColumnPadding(buf, start, h.opts.SourceWidth, &h.widths.source)
 				buf.WriteByte(' ')
 			}
 		}
 	}
 
 	// write message
+	msgWidth := h.opts.MessageWidth
+	if r.NumAttrs() == 0 && len(h.attrsPrefix) == 0 {
+		msgWidth = 0 // don't pad the end of the line
+	}
+	msgStart, msgEnd := len(*buf), -1
 	if rep == nil {
+		h.appendStyle(buf, Style{}, h.theme.Message)
 		buf.WriteString(r.Message)
+		h.appendStyle(buf, h.theme.Message, Style{})
+		msgEnd = len(*buf)
+		h.appendColumnPadding(buf, msgStart, msgWidth, &h.widths.message)
 		buf.WriteByte(' ')
 	} else if a := rep(nil /* groups */, slog.String(slog.MessageKey, r.Message)); a.Key != "" {
-		val, color := h.resolve(a.Value)
-		h.appendTintValue(buf, val, false, color, false)
+		val, style := h.resolve(a.Value)
+		h.appendTintValue(buf, val, false, h.theme.Message.with(style))
+		msgEnd = len(*buf)
+		h.appendColumnPadding(buf, msgStart, msgWidth, &h.widths.message)
 		buf.WriteByte(' ')
 	}
 
+	l := h.newLayout()
+	attrsStart := len(*buf)
+
 	// write handler attributes
 	if len(h.attrsPrefix) > 0 {
 		buf.WriteString(h.attrsPrefix)
 	}
+	if l != nil {
+		for _, end := range h.attrsPrefixEnds {
+			l.ends = append(l.ends, attrsStart+end)
+		}
+		l.blocks.WriteString(h.attrsBlocks)
+		l.err, l.stack = h.attrsErr, h.attrsStack
+	}
 
 	// write attributes
 	r.Attrs(func(attr slog.Attr) bool {
-		h.appendAttr(buf, attr, h.groupPrefix, h.groups)
+		h.appendAttr(buf, attr, h.groupPrefix, h.groups, l)
 		return true
 	})
 
+	// don't pad the end of the line if all attributes are written below it
+	if msgEnd >= 0 && len(*buf) == attrsStart {
+		*buf = append((*buf)[:msgEnd], ' ')
+	}
+
+	// write stack trace below the line
+	if l != nil && h.opts.StackTraceLevel != nil && (l.stack || r.Level >= h.opts.StackTraceLevel.Level()) {
+		var pcs []uintptr
+		if l.err != nil {
+			pcs = errorStack(l.err)
+		}
+		if pcs == nil && r.PC != 0 {
+			pcs = callerStack(r.PC)
+		}
+		h.appendStack(&l.blocks, pcs)
+	}
+
+	// wrap lines
+	if l != nil && h.opts.LineWidth > 0 && visibleWidth(*buf)-1 > h.opts.LineWidth {
+		wrapped := newBuffer()
+		defer wrapped.Free()
+
+		h.wrap(wrapped, *buf, msgStart, attrsStart, l.ends)
+		buf = wrapped
+	}
+
 	if len(*buf) == 0 {
 		buf.WriteByte('\n')
 	} else {
 		(*buf)[len(*buf)-1] = '\n' // replace last space with newline
 	}
 
+	// write blocks below the line
+	if l != nil {
+		buf.Write(l.blocks)
+	}
+
 	h.mu.Lock()
 	defer h.mu.Unlock()
 
@@ -300,115 +698,243 @@ func (h *handler) Handle(_ context.Context, r slog.Record) error {
 	return err
 }
 
-func (h *handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+func (h *Handler) WithAttrs(attrs []slog.Attr) slog.Handler {
 	if len(attrs) == 0 {
 		return h
 	}
+	return h.current().withAttrs(attrs)
+}
+
+func (h *Handler) withAttrs(attrs []slog.Attr) *Handler {
 	h2 := h.clone()
+	h2.ops = append(slices.Clip(h.ops), op{attrs: attrs})
+	if h.format != nil {
+		h2.format = h.format.WithAttrs(attrs)
+		return h2
+	}
 
 	buf := newBuffer()
 	defer buf.Free()
 
+	l := h.newLayout()
+
 	// write attributes to buffer
 	for _, attr := range attrs {
-		h.appendAttr(buf, attr, h.groupPrefix, h.groups)
+		h.appendAttr(buf, attr, h.groupPrefix, h.groups, l)
 	}
 	h2.attrsPrefix = h.attrsPrefix + string(*buf)
+	if l != nil {
+		h2.attrsPrefixEnds = slices.Clip(h.attrsPrefixEnds)
+		for _, end := range l.ends {
+			h2.attrsPrefixEnds = append(h2.attrsPrefixEnds, len(h.attrsPrefix)+end)
+		}
+		h2.attrsBlocks = h.attrsBlocks + string(l.blocks)
+		if h2.attrsErr == nil {
+			h2.attrsErr = l.err
+		}
+		h2.attrsStack = h.attrsStack || l.stack
+	}
 	return h2
 }
 
-func (h *handler) WithGroup(name string) slog.Handler {
+// newLayout returns a new layout, or nil if the handler doesn't need one.
+func (h *Handler) newLayout() *layout {
+	if h.opts.LineWidth > 0 || h.opts.MultilineValues || h.opts.ExpandErrors || h.opts.StackTraceLevel != nil {
+		return &layout{}
+	}
+	return nil
+}
+
+// wrap writes line to buf, wrapped at Options.LineWidth between the attributes
+// that end at the given offsets. Continuation lines are indented to the column
+// of the message.
+func (h *Handler) wrap(buf *buffer, line []byte, msgStart, attrsStart int, ends []int) {
+	indent := visibleWidth(line[:msgStart])
+	if indent >= h.opts.LineWidth {
+		indent = 0
+	}
+
+	buf.Write(line[:attrsStart])
+	col := visibleWidth(line[:attrsStart])
+	start := attrsStart
+	for _, end := range ends {
+		attr := line[start:end]
+		width := visibleWidth(attr) - 1 // without the trailing space
+		if col > indent && col+width > h.opts.LineWidth {
+			// replace trailing spaces, e.g. of the message padding, with newline
+			for len(*buf) > 0 && (*buf)[len(*buf)-1] == ' ' {
+				*buf = (*buf)[:len(*buf)-1]
+			}
+			buf.WriteByte('\n')
+			appendPadding(buf, indent)
+			col = indent
+		}
+		buf.Write(attr)
+		col += width + 1
+		start = end
+	}
+	buf.Write(line[start:])
+}
+
+func (h *Handler) WithGroup(name string) slog.Handler {
 	if name == "" {
 		return h
 	}
+	return h.current().withGroup(name)
+}
+
+func (h *Handler) withGroup(name string) *Handler {
 	h2 := h.clone()
-	h2.groupPrefix += name + "."
+	h2.ops = append(slices.Clip(h.ops), op{group: name})
+	h2.groupPrefix += name + "." // also used by Options.LevelSpec in all formats
 	h2.groups = append(h2.groups, name)
+	if h.format != nil {
+		h2.format = h.format.WithGroup(name)
+	}
 	return h2
 }
 
-func (h *handler) appendTintTime(buf *buffer, t time.Time, color int16) {
-	if h.opts.NoColor {
-		*buf = t.AppendFormat(*buf, h.opts.TimeFormat)
+func (h *Handler) appendTintTime(buf *buffer, t time.Time, style Style) {
+	style = h.theme.Time.with(style)
+	h.appendStyle(buf, Style{}, style)
+	h.appendTimeMode(buf, t)
+	h.appendStyle(buf, style, Style{})
+}
+
+func (h *Handler) appendTintLevel(buf *buffer, level slog.Level, style Style) {
+	l := findLevel(h.levels, level)
+
+	if l.Style != (Style{}) {
+		style = l.Style.with(style)
 	} else {
-		if color >= 0 {
-			appendAnsi(buf, uint8(color), true)
-		} else {
-			buf.WriteString(ansiFaint)
-		}
-		*buf = t.AppendFormat(*buf, h.opts.TimeFormat)
-		buf.WriteString(ansiReset)
+		style = h.theme.level(l.Level).with(style)
+	}
+	badge := h.opts.LevelBadge && !h.opts.NoColor
+	if badge && style.Background == 0 {
+		style.Inverse = true
+	}
+	h.appendStyle(buf, Style{}, style)
+	if badge {
+		buf.WriteByte(' ')
 	}
-}
 
-func (h *handler) appendTintLevel(buf *buffer, level slog.Level, color int16) {
-	str := func(base string, val slog.Level) []byte {
-		if val == 0 {
-			return []byte(base)
-		} else if val > 0 {
-			return strconv.AppendInt(append([]byte(base), '+'), int64(val), 10)
-		}
-		return strconv.AppendInt([]byte(base), int64(val), 10)
+	start := len(*buf)
+	*buf = appendLevelLabel(*buf, l, level)
+	n := utf8.RuneCount((*buf)[start:])
+	pad := fit(h.opts.LevelWidth, n, &h.widths.level) - n
+
+	if badge {
+		appendPadding(buf, pad)
+		buf.WriteByte(' ')
+	}
+	h.appendStyle(buf, style, Style{})
+	if !badge {
+		appendPadding(buf, pad)
 	}
+}
 
-	if !h.opts.NoColor {
-		if color >= 0 {
-			appendAnsi(buf, uint8(color), false)
-		} else {
-			switch {
-			case level < slog.LevelInfo:
-			case level < slog.LevelWarn:
-				buf.WriteString(ansiBrightGreen)
-			case level < slog.LevelError:
-				buf.WriteString(ansiBrightYellow)
-			default:
-				buf.WriteString(ansiBrightRed)
-			}
+// findLevel returns the level of levels with the highest threshold that is less
+// than or equal to level, or the lowest level.
+func findLevel(levels []Level, level slog.Level) Level {
+	l := levels[0]
+	for _, next := range levels[1:] {
+		if next.Level > level {
+			break
 		}
+		l = next
 	}
+	return l
+}
 
-	switch {
-	case level < slog.LevelInfo:
-		buf.Write(str("DBG", level-slog.LevelDebug))
-	case level < slog.LevelWarn:
-		buf.Write(str("INF", level-slog.LevelInfo))
-	case level < slog.LevelError:
-		buf.Write(str("WRN", level-slog.LevelWarn))
-	default:
-		buf.Write(str("ERR", level-slog.LevelError))
+// appendLevelLabel appends the label of level to buf, i.e. the label of the
+// level l found by findLevel and the offset from it, e.g. "INF+2".
+func appendLevelLabel(buf []byte, l Level, level slog.Level) []byte {
+	buf = append(buf, l.Label...)
+	if diff := level - l.Level; diff > 0 {
+		buf = strconv.AppendInt(append(buf, '+'), int64(diff), 10)
+	} else if diff < 0 {
+		buf = strconv.AppendInt(buf, int64(diff), 10)
 	}
+	return buf
+}
 
-	if !h.opts.NoColor && (color >= 0 || level >= slog.LevelInfo) {
-		buf.WriteString(ansiReset)
+// appendColumnPadding pads the column written to buf since start with spaces
+// to the given width.
+func (h *Handler) appendColumnPadding(buf *buffer, start, width int, maxWidth *atomic.Int64) {
+	if width == 0 {
+		return
 	}
+	n := visibleWidth((*buf)[start:])
+	appendPadding(buf, fit(width, n, maxWidth)-n)
 }
 
-func appendSource(buf *buffer, src *slog.Source) {
-	dir, file := filepath.Split(src.File)
+// visibleWidth returns the number of runes in b, excluding ANSI escape
+// sequences.
+func visibleWidth(b []byte) int {
+	var (
+		n        int
+		inEscape bool
+	)
+	for len(b) > 0 {
+		r, size := utf8.DecodeRune(b)
+		b = b[size:]
+		if r == ansiEsc && len(b) > 0 && b[0] == ']' {
+			// skip OSC sequence, e.g. a hyperlink, terminated by ST (ESC \) or BEL
+			i := strings.IndexAny(string(b), "\a\u001b")
+			if i < 0 {
+				break
+			}
+			if b[i] == ansiEsc {
+				i++
+			}
+			b = b[min(i+1, len(b)):]
+		} else if r == ansiEsc {
+			inEscape = true
+		} else if inEscape {
+			inEscape = !unicode.IsLetter(r)
+		} else {
+			n++
+		}
+	}
+	return n
+}
 
-	buf.WriteString(filepath.Join(filepath.Base(dir), file))
-	buf.WriteByte(':')
-	*buf = strconv.AppendInt(*buf, int64(src.Line), 10)
+// appendPadding writes n spaces.
+func appendPadding(buf *buffer, n int) {
+	for ; n > 0; n-- {
+		buf.WriteByte(' ')
+	}
 }
 
-func (h *handler) resolve(val slog.Value) (resolvedVal slog.Value, color int16) {
+// resolve resolves the value and returns the style of a tinted value, or the
+// zero Style if the value is not tinted.
+func (h *Handler) resolve(val slog.Value) (resolvedVal slog.Value, style Style) {
 	if !h.opts.NoColor && val.Kind() == slog.KindLogValuer {
 		if tintVal, ok := val.Any().(tintValue); ok {
-			return tintVal.Value.Resolve(), int16(tintVal.Color)
+			if tintVal.Err {
+				return tintVal.Value.Resolve(), h.theme.Err
+			}
+			return tintVal.Value.Resolve(), h.opts.ColorProfile.convertStyle(tintVal.Style)
 		}
 	}
-	return val.Resolve(), -1
+	return val.Resolve(), Style{}
 }
 
-func (h *handler) appendAttr(buf *buffer, attr slog.Attr, groupsPrefix string, groups []string) {
-	var color int16 // -1 if no color
-	attr.Value, color = h.resolve(attr.Value)
+func (h *Handler) appendAttr(buf *buffer, attr slog.Attr, groupsPrefix string, groups []string, l *layout) {
+	if l != nil && attr.Value.Kind() == slog.KindLogValuer {
+		if tintVal, ok := attr.Value.Any().(tintValue); ok && tintVal.Err {
+			l.stack = true
+		}
+	}
+
+	var style Style // zero if not tinted
+	attr.Value, style = h.resolve(attr.Value)
 	if rep := h.opts.ReplaceAttr; rep != nil && attr.Value.Kind() != slog.KindGroup {
 		attr = rep(groups, attr)
-		var colorRep int16
-		attr.Value, colorRep = h.resolve(attr.Value)
-		if colorRep >= 0 {
-			color = colorRep
+		var styleRep Style
+		attr.Value, styleRep = h.resolve(attr.Value)
+		if styleRep != (Style{}) {
+			style = styleRep
 		}
 	}
 
@@ -422,37 +948,83 @@ func (h *handler) appendAttr(buf *buffer, attr slog.Attr, groupsPrefix string, g
 			groups = append(groups, attr.Key)
 		}
 		for _, groupAttr := range attr.Value.Group() {
-			h.appendAttr(buf, groupAttr, groupsPrefix, groups)
+			h.appendAttr(buf, groupAttr, groupsPrefix, groups, l)
 		}
 		return
 	}
 
-	if h.opts.NoColor {
-		h.appendKey(buf, attr.Key, groupsPrefix)
-		h.appendValue(buf, attr.Value, true)
-	} else {
-		if color >= 0 {
-			appendAnsi(buf, uint8(color), true)
-			h.appendKey(buf, attr.Key, groupsPrefix)
-			buf.WriteString(ansiResetFaint)
-			h.appendValue(buf, attr.Value, true)
-			buf.WriteString(ansiReset)
-		} else {
-			buf.WriteString(ansiFaint)
-			h.appendKey(buf, attr.Key, groupsPrefix)
-			buf.WriteString(ansiReset)
-			h.appendValue(buf, attr.Value, true)
+	if l != nil && l.err == nil && attr.Value.Kind() == slog.KindAny {
+		l.err, _ = attr.Value.Any().(error)
+	}
+
+	theme := h.theme
+	groupStyle, keyStyle, valStyle := theme.Group.with(style), theme.Key.with(style), theme.Value.with(style)
+
+	if l != nil && h.opts.MultilineValues && attr.Value.Kind() == slog.KindString && strings.Contains(attr.Value.String(), "\n") {
+		h.appendBlock(&l.blocks, attr.Key, groupsPrefix, attr.Value.String(), keyStyle, valStyle)
+		return
+	}
+	if l != nil && h.opts.ExpandErrors && attr.Value.Kind() == slog.KindAny {
+		if err, ok := attr.Value.Any().(error); ok && len(unwrapErr(err)) > 0 {
+			h.appendErrorBlock(&l.blocks, attr.Key, groupsPrefix, err, keyStyle, valStyle)
+			return
 		}
 	}
+
+	var prev Style
+	if groupsPrefix != "" && groupStyle != keyStyle && !needsQuoting(groupsPrefix+attr.Key) {
+		h.appendStyle(buf, prev, groupStyle)
+		buf.WriteString(groupsPrefix)
+		groupsPrefix, prev = "", groupStyle
+	}
+	h.appendStyle(buf, prev, keyStyle)
+	h.appendKey(buf, attr.Key, groupsPrefix)
+	h.appendStyle(buf, keyStyle, valStyle)
+	h.appendValue(buf, attr.Value, true, valStyle)
+	h.appendStyle(buf, valStyle, Style{})
 	buf.WriteByte(' ')
+	if l != nil {
+		l.ends = append(l.ends, len(*buf))
+	}
 }
 
-func (h *handler) appendKey(buf *buffer, key, groups string) {
+// appendBlock writes the multi-line value of an attribute as a block of lines,
+// each indented and marked with a gutter.
+func (h *Handler) appendBlock(buf *buffer, key, groupsPrefix, val string, keyStyle, valStyle Style) {
+	h.appendBlockKey(buf, key, groupsPrefix, keyStyle)
+	for _, line := range strings.Split(strings.TrimSuffix(val, "\n"), "\n") {
+		h.appendGutter(buf)
+		h.appendStyle(buf, Style{}, valStyle)
+		buf.WriteString(strings.TrimSuffix(line, "\r"))
+		h.appendStyle(buf, valStyle, Style{})
+		buf.WriteByte('\n')
+	}
+}
+
+// appendBlockKey writes the key line of a block.
+func (h *Handler) appendBlockKey(buf *buffer, key, groupsPrefix string, keyStyle Style) {
+	buf.WriteString("  ")
+	h.appendStyle(buf, Style{}, keyStyle)
+	h.appendKey(buf, key, groupsPrefix)
+	h.appendStyle(buf, keyStyle, Style{})
+	buf.WriteByte('\n')
+}
+
+// appendGutter writes the indented gutter at the start of a line of a block.
+func (h *Handler) appendGutter(buf *buffer) {
+	buf.WriteString("  ")
+	h.appendStyle(buf, Style{}, h.theme.Gutter)
+	buf.WriteString("│")
+	h.appendStyle(buf, h.theme.Gutter, Style{})
+	buf.WriteByte(' ')
+}
+
+func (h *Handler) appendKey(buf *buffer, key, groups string) {
 	appendString(buf, groups+key, true, !h.opts.NoColor)
 	buf.WriteByte('=')
 }
 
-func (h *handler) appendValue(buf *buffer, v slog.Value, quote bool) {
+func (h *Handler) appendValue(buf *buffer, v slog.Value, quote bool, style Style) {
 	switch v.Kind() {
 	case slog.KindString:
 		appendString(buf, v.String(), quote, !h.opts.NoColor)
@@ -465,9 +1037,13 @@ func (h *handler) appendValue(buf *buffer, v slog.Value, quote bool) {
 	case slog.KindBool:
 		*buf = strconv.AppendBool(*buf, v.Bool())
 	case slog.KindDuration:
-		appendString(buf, v.Duration().String(), quote, !h.opts.NoColor)
+		h.appendDuration(buf, v.Duration(), style)
 	case slog.KindTime:
-		*buf = appendRFC3339Millis(*buf, v.Time())
+		if h.opts.AttrTimeFormat != "" {
+			appendString(buf, h.inLocation(v.Time()).Format(h.opts.AttrTimeFormat), quote, !h.opts.NoColor)
+		} else {
+			*buf = appendRFC3339Millis(*buf, h.inLocation(v.Time()))
+		}
 	case slog.KindAny:
 		defer func() {
 			// Copied from log/slog/handler.go.
@@ -495,26 +1071,42 @@ func (h *handler) appendValue(buf *buffer, v slog.Value, quote bool) {
 			}
 			appendString(buf, string(data), quote, !h.opts.NoColor)
 		case *slog.Source:
-			appendSource(buf, cv)
+			h.appendSource(buf, cv)
+		case json.Marshaler:
+			if h.opts.JSONValues {
+				if data, err := cv.MarshalJSON(); err == nil && h.appendJSON(buf, data, style) {
+					break
+				}
+			}
+			h.appendAny(buf, cv, quote, style)
 		default:
-			appendString(buf, fmt.Sprintf("%+v", cv), quote, !h.opts.NoColor)
+			h.appendAny(buf, cv, quote, style)
 		}
 	}
 }
 
-func (h *handler) appendTintValue(buf *buffer, val slog.Value, quote bool, color int16, faint bool) {
-	if h.opts.NoColor {
-		h.appendValue(buf, val, quote)
-	} else {
-		if color >= 0 {
-			appendAnsi(buf, uint8(color), faint)
-		} else if faint {
-			buf.WriteString(ansiFaint)
-		}
-		h.appendValue(buf, val, quote)
-		if color >= 0 || faint {
-			buf.WriteString(ansiReset)
-		}
+// appendAny writes a value of kind [slog.KindAny] that has no special
+// formatting.
+func (h *Handler) appendAny(buf *buffer, v any, quote bool, style Style) {
+	if h.opts.PrettyValues && isPretty(v) {
+		h.appendPretty(buf, reflect.ValueOf(v), style, 0)
+		return
+	}
+	appendString(buf, fmt.Sprintf("%+v", v), quote, !h.opts.NoColor)
+}
+
+func (h *Handler) appendTintValue(buf *buffer, val slog.Value, quote bool, style Style) {
+	h.appendStyle(buf, Style{}, style)
+	h.appendValue(buf, val, quote, style)
+	h.appendStyle(buf, style, Style{})
+}
+
+// appendStyle writes the ANSI escape sequence that switches from style from to
+// style to, unless color is disabled. Colors must already be converted to the
+// color profile of the handler.
+func (h *Handler) appendStyle(buf *buffer, from, to Style) {
+	if !h.opts.NoColor {
+		appendStyle(buf, from, to)
 	}
 }
 
@@ -532,22 +1124,6 @@ func appendRFC3339Millis(b []byte, t time.Time) []byte {
 	return b
 }
 
-func appendAnsi(buf *buffer, color uint8, faint bool) {
-	buf.WriteString("\u001b[")
-	if faint {
-		buf.WriteString("2;")
-	}
-	if color < 8 {
-		*buf = strconv.AppendUint(*buf, uint64(color)+30, 10)
-	} else if color < 16 {
-		*buf = strconv.AppendUint(*buf, uint64(color)+82, 10)
-	} else {
-		buf.WriteString("38;5;")
-		*buf = strconv.AppendUint(*buf, uint64(color), 10)
-	}
-	buf.WriteByte('m')
-}
-
 func appendString(buf *buffer, s string, quote, color bool) {
 	if quote && !color {
 		// trim ANSI escape sequences
@@ -722,7 +1298,8 @@ var safeSet = [utf8.RuneSelf]bool{
 
 type tintValue struct {
 	slog.Value
-	Color uint8
+	Style Style
+	Err   bool // use the Theme.Err style
 }
 
 // LogValue implements the [slog.LogValuer] interface.
@@ -730,12 +1307,13 @@ func (v tintValue) LogValue() slog.Value {
 	return v.Value
 }
 
-// Err returns a tinted (colorized) [slog.Attr] that will be written in red color
-// by the [tint.Handler]. When used with any other [slog.Handler], it behaves as
+// Err returns a tinted (colorized) [slog.Attr] that will be written in the
+// Theme.Err style (Default: red) by the [tint.Handler]. When used with any other
+// [slog.Handler], it behaves as
 //
 //	slog.Any("err", err)
 func Err(err error) slog.Attr {
-	return Attr(9, slog.Any(errKey, err))
+	return slog.Any(errKey, tintValue{Value: slog.AnyValue(err), Err: true})
 }
 
 // Attr returns a tinted (colorized) [slog.Attr] that will be written in the
@@ -751,6 +1329,22 @@ func Err(err error) slog.Attr {
 //
 // See https://en.wikipedia.org/wiki/ANSI_escape_code#8-bit
 func Attr(color uint8, attr slog.Attr) slog.Attr {
-	attr.Value = slog.AnyValue(tintValue{attr.Value, color})
+	return ColorAttr(ANSIColor(color), attr)
+}
+
+// ColorAttr returns a tinted (colorized) [slog.Attr] that will be written in the
+// specified [Color] by the [tint.Handler]. Unlike [Attr], it also accepts 24-bit
+// colors created with [RGB] or [HexColor]. When used with any other
+// [slog.Handler], it behaves as a plain [slog.Attr].
+func ColorAttr(color Color, attr slog.Attr) slog.Attr {
+	return Styled(Style{Foreground: color}, attr)
+}
+
+// Styled returns a tinted (styled) [slog.Attr] that will be written in the
+// specified [Style] by the [tint.Handler]. The style is applied on top of the
+// Theme.Key and Theme.Value styles. When used with any other [slog.Handler], it
+// behaves as a plain [slog.Attr].
+func Styled(style Style, attr slog.Attr) slog.Attr {
+	attr.Value = slog.AnyValue(tintValue{Value: attr.Value, Style: style})
 	return attr
 }
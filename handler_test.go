@@ -3,10 +3,16 @@ package tint_test
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
+	"flag"
+	"fmt"
 	"io"
 	"log/slog"
+	"math"
 	"os"
+	"path/filepath"
+	"runtime"
 	"slices"
 	"strconv"
 	"strings"
@@ -54,14 +60,8 @@ func Example_traceLevel() {
 	w := os.Stderr
 	logger := slog.New(tint.NewHandler(w, &tint.Options{
 		Level: LevelTrace,
-		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
-			if a.Key == slog.LevelKey && len(groups) == 0 {
-				level, ok := a.Value.Any().(slog.Level)
-				if ok && level <= LevelTrace {
-					return tint.Attr(13, slog.String(a.Key, "TRC"))
-				}
-			}
-			return a
+		Levels: []tint.Level{
+			{Level: LevelTrace, Label: "TRC", Style: tint.Style{Foreground: tint.ANSIColor(13)}},
 		},
 	}))
 	logger.Log(context.Background(), LevelTrace, "DB query", "query", "SELECT * FROM users", "duration", 543*time.Microsecond)
@@ -649,6 +649,397 @@ var (
 			},
 			Want: "\033[2mNov 10 23:00:00.000\033[0m \033[95mDBG\033[0m \033[2mtint/handler_test.go:648\033[0m test",
 		},
+		{
+			Opts: &tint.Options{Theme: tint.LightTheme()},
+			F: func(l *slog.Logger) {
+				l.Error("test", "key", "val", tint.Err(errors.New("fail")))
+			},
+			Want: "\033[38;5;240mNov 10 23:00:00.000\033[0m \033[31mERR\033[0m test \033[34mkey=\033[0mval \033[31merr=fail\033[0m",
+		},
+		{
+			Opts: &tint.Options{
+				Theme: &tint.Theme{
+					Info:    tint.Style{Foreground: tint.ANSIColor(2)},
+					Message: tint.Style{Foreground: tint.ANSIColor(15)},
+					Key:     tint.Style{Foreground: tint.ANSIColor(4)},
+					Value:   tint.Style{Foreground: tint.ANSIColor(6)},
+					Group:   tint.Style{Faint: true},
+				},
+			},
+			F: func(l *slog.Logger) {
+				l.WithGroup("group").Info("test", "key", "val", tint.Attr(13, slog.String("key2", "val2")))
+			},
+			Want: "Nov 10 23:00:00.000 \033[32mINF\033[0m \033[97mtest\033[0m \033[2mgroup.\033[22;34mkey=\033[36mval\033[0m \033[2;95mgroup.\033[22mkey2=val2\033[0m",
+		},
+		{
+			Opts: &tint.Options{NoColor: false},
+			F: func(l *slog.Logger) {
+				l.Info("test", tint.ColorAttr(tint.RGB(255, 128, 0), slog.String("key", "val")))
+			},
+			Want: "\033[2mNov 10 23:00:00.000\033[0m \033[92mINF\033[0m test \033[2;38;2;255;128;0mkey=\033[22mval\033[0m",
+		},
+		{
+			Opts: &tint.Options{ColorProfile: tint.ColorProfile256},
+			F: func(l *slog.Logger) {
+				l.Info("test", tint.ColorAttr(tint.RGB(255, 128, 0), slog.String("key", "val")))
+			},
+			Want: "\033[2mNov 10 23:00:00.000\033[0m \033[92mINF\033[0m test \033[2;38;5;208mkey=\033[22mval\033[0m",
+		},
+		{
+			Opts: &tint.Options{ColorProfile: tint.ColorProfile16},
+			F: func(l *slog.Logger) {
+				l.Info("test", tint.Attr(196, slog.String("key", "val")))
+			},
+			Want: "\033[2mNov 10 23:00:00.000\033[0m \033[92mINF\033[0m test \033[2;91mkey=\033[22mval\033[0m",
+		},
+		{
+			Opts: &tint.Options{ColorProfile: tint.ColorProfileNone},
+			F: func(l *slog.Logger) {
+				l.Error("test", tint.Err(errors.New("fail")))
+			},
+			Want: "\033[2mNov 10 23:00:00.000\033[0m ERR test \033[2merr=\033[0mfail",
+		},
+		{
+			Opts: &tint.Options{NoColor: false},
+			F: func(l *slog.Logger) {
+				l.Info("test", tint.Styled(tint.Style{
+					Foreground: tint.ANSIColor(15),
+					Background: tint.ANSIColor(1),
+					Bold:       true,
+					Underline:  true,
+				}, slog.String("key", "val")))
+			},
+			Want: "\033[2mNov 10 23:00:00.000\033[0m \033[92mINF\033[0m test \033[1;2;4;97;41mkey=\033[22;1mval\033[0m",
+		},
+		{
+			Opts: &tint.Options{
+				Theme: &tint.Theme{
+					Key:   tint.Style{Italic: true, Inverse: true},
+					Value: tint.Style{Strikethrough: true, Background: tint.RGB(0, 0, 255)},
+				},
+				ReplaceAttr: drop(slog.TimeKey, slog.LevelKey, slog.MessageKey),
+			},
+			F: func(l *slog.Logger) {
+				l.Info("test", "key", "val")
+			},
+			Want: "\033[3;7mkey=\033[23;27;9;48;2;0;0;255mval\033[0m",
+		},
+		{
+			Opts: &tint.Options{LevelBadge: true},
+			F: func(l *slog.Logger) {
+				l.Error("test")
+			},
+			Want: "\033[2mNov 10 23:00:00.000\033[0m \033[7;91m ERR \033[0m test",
+		},
+		{
+			Opts: &tint.Options{
+				LevelBadge: true,
+				Theme: &tint.Theme{
+					Warn: tint.Style{Foreground: tint.ANSIColor(0), Background: tint.ANSIColor(11)},
+				},
+			},
+			F: func(l *slog.Logger) {
+				l.Warn("test")
+			},
+			Want: "Nov 10 23:00:00.000 \033[30;103m WRN \033[0m test",
+		},
+		{
+			Opts: &tint.Options{LevelBadge: true, NoColor: true},
+			F: func(l *slog.Logger) {
+				l.Error("test")
+			},
+			Want: "Nov 10 23:00:00.000 ERR test",
+		},
+		{
+			Opts: &tint.Options{
+				Level: slog.LevelDebug - 8,
+				Levels: []tint.Level{
+					{Level: slog.LevelDebug - 4, Label: "TRC", Style: tint.Style{Foreground: tint.ANSIColor(13)}},
+					{Level: slog.LevelError + 4, Label: "FTL", Style: tint.Style{Foreground: tint.ANSIColor(15), Background: tint.ANSIColor(1)}},
+				},
+			},
+			F: func(l *slog.Logger) {
+				l.Log(context.TODO(), slog.LevelDebug-5, "test")
+				l.Log(context.TODO(), slog.LevelDebug-4, "test")
+				l.Log(context.TODO(), slog.LevelDebug-3, "test")
+				l.Log(context.TODO(), slog.LevelDebug, "test")
+				l.Log(context.TODO(), slog.LevelError+3, "test")
+				l.Log(context.TODO(), slog.LevelError+4, "test")
+			},
+			Want: "\033[2mNov 10 23:00:00.000\033[0m \033[95mTRC-1\033[0m test\n" +
+				"\033[2mNov 10 23:00:00.000\033[0m \033[95mTRC\033[0m test\n" +
+				"\033[2mNov 10 23:00:00.000\033[0m \033[95mTRC+1\033[0m test\n" +
+				"\033[2mNov 10 23:00:00.000\033[0m DBG test\n" +
+				"\033[2mNov 10 23:00:00.000\033[0m \033[91mERR+3\033[0m test\n" +
+				"\033[2mNov 10 23:00:00.000\033[0m \033[97;41mFTL\033[0m test",
+		},
+		{
+			Opts: &tint.Options{
+				Levels:  []tint.Level{{Level: slog.LevelInfo, Label: "INFO"}},
+				NoColor: false,
+			},
+			F: func(l *slog.Logger) {
+				l.Info("test")
+				l.Log(context.TODO(), slog.LevelInfo+1, "test")
+			},
+			Want: "\033[2mNov 10 23:00:00.000\033[0m \033[92mINFO\033[0m test\n" +
+				"\033[2mNov 10 23:00:00.000\033[0m \033[92mINFO+1\033[0m test",
+		},
+		{
+			Opts: &tint.Options{
+				Level:       slog.LevelDebug,
+				LevelFormat: tint.LevelFormatLong,
+				LevelWidth:  5,
+				NoColor:     true,
+			},
+			F: func(l *slog.Logger) {
+				l.Debug("test")
+				l.Info("test")
+				l.Warn("test")
+				l.Log(context.TODO(), slog.LevelError+2, "test")
+			},
+			Want: "Nov 10 23:00:00.000 DEBUG test\n" +
+				"Nov 10 23:00:00.000 INFO  test\n" +
+				"Nov 10 23:00:00.000 WARN  test\n" +
+				"Nov 10 23:00:00.000 ERROR+2 test",
+		},
+		{
+			Opts: &tint.Options{
+				LevelFormat: tint.LevelFormatLetter,
+				NoColor:     true,
+			},
+			F: func(l *slog.Logger) {
+				l.Info("test")
+				l.Log(context.TODO(), slog.LevelWarn+1, "test")
+			},
+			Want: "Nov 10 23:00:00.000 I test\n" +
+				"Nov 10 23:00:00.000 W+1 test",
+		},
+		{
+			Opts: &tint.Options{LevelWidth: 5},
+			F: func(l *slog.Logger) {
+				l.Info("test")
+			},
+			Want: "\033[2mNov 10 23:00:00.000\033[0m \033[92mINF\033[0m   test",
+		},
+		{
+			Opts: &tint.Options{LevelWidth: 5, LevelBadge: true},
+			F: func(l *slog.Logger) {
+				l.Info("test")
+			},
+			Want: "\033[2mNov 10 23:00:00.000\033[0m \033[7;92m INF   \033[0m test",
+		},
+		{
+			Opts: &tint.Options{
+				AddSource:    true,
+				SourceWidth:  8,
+				MessageWidth: 6,
+				ReplaceAttr:  replace(slog.StringValue("a.go:1"), slog.SourceKey),
+			},
+			F: func(l *slog.Logger) {
+				l.Info("test", "key", "val")
+				l.Info("test")
+			},
+			Want: "\033[2mNov 10 23:00:00.000\033[0m \033[92mINF\033[0m \033[2ma.go:1\033[0m   test   \033[2mkey=\033[0mval\n" +
+				"\033[2mNov 10 23:00:00.000\033[0m \033[92mINF\033[0m \033[2ma.go:1\033[0m   test",
+		},
+		{
+			Opts: &tint.Options{
+				LevelWidth:   tint.AdaptiveWidth,
+				MessageWidth: tint.AdaptiveWidth,
+				NoColor:      true,
+			},
+			F: func(l *slog.Logger) {
+				l.Info("a", "key", "val")
+				l.Log(context.TODO(), slog.LevelInfo+2, "long message", "key", "val")
+				l.With("key", "val").Info("b")
+			},
+			Want: "Nov 10 23:00:00.000 INF a key=val\n" +
+				"Nov 10 23:00:00.000 INF+2 long message key=val\n" +
+				"Nov 10 23:00:00.000 INF   b            key=val",
+		},
+		{
+			Opts: &tint.Options{
+				LineWidth: 40,
+				NoColor:   true,
+			},
+			F: func(l *slog.Logger) {
+				l.With("a", 1, "b", 2).WithGroup("group").Info("test", "key", "val", "key2", "value2", "k", "v")
+				l.Info("short", "key", "val")
+			},
+			Want: "Nov 10 23:00:00.000 INF test a=1 b=2\n" +
+				"                        group.key=val\n" +
+				"                        group.key2=value2\n" +
+				"                        group.k=v\n" +
+				"Nov 10 23:00:00.000 INF short key=val",
+		},
+		{
+			Opts: &tint.Options{LineWidth: 36},
+			F: func(l *slog.Logger) {
+				l.Info("test", "key", "val", "key2", "val2")
+			},
+			Want: "\033[2mNov 10 23:00:00.000\033[0m \033[92mINF\033[0m test \033[2mkey=\033[0mval\n" +
+				"                        \033[2mkey2=\033[0mval2",
+		},
+		{
+			Opts: &tint.Options{
+				LineWidth:    30,
+				MessageWidth: 10,
+				NoColor:      true,
+			},
+			F: func(l *slog.Logger) {
+				l.Info("msg", "key", "a long value")
+			},
+			Want: "Nov 10 23:00:00.000 INF msg\n" +
+				"                        key=\"a long value\"",
+		},
+		{
+			Opts: &tint.Options{
+				MultilineValues: true,
+				NoColor:         true,
+			},
+			F: func(l *slog.Logger) {
+				l.With("body", "{\n  \"id\": 1\n}\n").Info("test", "query", "SELECT *\r\nFROM users", "key", "val")
+			},
+			Want: "Nov 10 23:00:00.000 INF test key=val\n" +
+				"  body=\n" +
+				"  │ {\n" +
+				"  │   \"id\": 1\n" +
+				"  │ }\n" +
+				"  query=\n" +
+				"  │ SELECT *\n" +
+				"  │ FROM users",
+		},
+		{
+			Opts: &tint.Options{MultilineValues: true},
+			F: func(l *slog.Logger) {
+				l.Info("test", "query", "SELECT *\nFROM users")
+			},
+			Want: "\033[2mNov 10 23:00:00.000\033[0m \033[92mINF\033[0m test\n" +
+				"  \033[2mquery=\033[0m\n" +
+				"  \033[2m│\033[0m SELECT *\n" +
+				"  \033[2m│\033[0m FROM users",
+		},
+		{
+			Opts: &tint.Options{
+				MessageWidth:    10,
+				MultilineValues: true,
+				NoColor:         true,
+			},
+			F: func(l *slog.Logger) {
+				l.Info("x", "query", "SELECT *\nFROM users")
+			},
+			Want: "Nov 10 23:00:00.000 INF x\n" +
+				"  query=\n" +
+				"  │ SELECT *\n" +
+				"  │ FROM users",
+		},
+		{
+			Opts: &tint.Options{
+				PrettyValues: true,
+				NoColor:      true,
+			},
+			F: func(l *slog.Logger) {
+				type user struct {
+					Name  string
+					Tags  []string
+					Meta  map[string]any
+					Born  time.Time
+					Err   error
+					Next  *int
+					count int
+				}
+				l.Info("test", "user", user{
+					Name:  "Jane Doe",
+					Tags:  []string{"a", "b"},
+					Meta:  map[string]any{"k": 1, "a": []int{1}},
+					Born:  testTime,
+					Err:   errTest,
+					count: 2,
+				}, "err", errTest)
+			},
+			Want: `Nov 10 23:00:00.000 INF test user={Name:"Jane Doe" Tags:[a b] Meta:{a:[1] k:1} Born:2022-05-01T00:00:00Z Err:fail Next:<nil> count:2} err=fail`,
+		},
+		{
+			Opts: &tint.Options{
+				PrettyValues:   true,
+				MaxValueDepth:  2,
+				MaxValueLength: 3,
+				NoColor:        true,
+			},
+			F: func(l *slog.Logger) {
+				l.Info("test", "slice", []int{1, 2, 3, 4, 5}, "nested", [][][]int{{{1}}})
+			},
+			Want: `Nov 10 23:00:00.000 INF test slice=[1 2 3 …] nested=[[[…]]]`,
+		},
+		{
+			Opts: &tint.Options{PrettyValues: true},
+			F: func(l *slog.Logger) {
+				l.Info("test", "map", map[string]int{"a": 1})
+				l.Info("test", tint.Attr(9, slog.Any("map", map[string]int{"a": 1})))
+			},
+			Want: "\033[2mNov 10 23:00:00.000\033[0m \033[92mINF\033[0m test \033[2mmap=\033[0m{\033[2ma:\033[0m1}\n" +
+				"\033[2mNov 10 23:00:00.000\033[0m \033[92mINF\033[0m test \033[2;91mmap=\033[22m{\033[2ma:\033[22m1}\033[0m",
+		},
+		{
+			Opts: &tint.Options{
+				JSONValues: true,
+				NoColor:    true,
+			},
+			F: func(l *slog.Logger) {
+				l.Info("test", "json", json.RawMessage(`{ "id": 1, "tags": ["a b", "c\"d"], "ok": true, "next": null }`))
+				l.Info("test", "json", failingMarshaler{A: 1})
+			},
+			Want: `Nov 10 23:00:00.000 INF test json={"id":1,"tags":["a b","c\"d"],"ok":true,"next":null}` + "\n" +
+				`Nov 10 23:00:00.000 INF test json={A:1}`,
+		},
+		{
+			Opts: &tint.Options{JSONValues: true},
+			F: func(l *slog.Logger) {
+				l.Info("test", "json", json.RawMessage(`{"id":-1.5e3,"ok":false}`))
+			},
+			Want: "\033[2mNov 10 23:00:00.000\033[0m \033[92mINF\033[0m test \033[2mjson=\033[0m{\033[94m\"id\"\033[0m:\033[36m-1.5e3\033[0m,\033[94m\"ok\"\033[0m:\033[35mfalse\033[0m}",
+		},
+		{
+			Opts: &tint.Options{
+				ExpandErrors: true,
+				NoColor:      true,
+			},
+			F: func(l *slog.Logger) {
+				err := fmt.Errorf("query users: %w", errors.Join(
+					fmt.Errorf("dial: %w", errTest),
+					errors.New("timeout"),
+				))
+				l.Error("test", tint.Err(err), "simple", errTest)
+			},
+			Want: "Nov 10 23:00:00.000 ERR test simple=fail\n" +
+				"  err=\n" +
+				"  │ query users (*fmt.wrapError)\n" +
+				"  │   (*errors.joinError)\n" +
+				"  │     dial (*fmt.wrapError)\n" +
+				"  │       fail (*errors.errorString)\n" +
+				"  │     timeout (*errors.errorString)",
+		},
+		{
+			Opts: &tint.Options{ExpandErrors: true},
+			F: func(l *slog.Logger) {
+				l.Error("test", tint.Err(fmt.Errorf("dial: %w", errTest)))
+			},
+			Want: "\033[2mNov 10 23:00:00.000\033[0m \033[91mERR\033[0m test\n" +
+				"  \033[2;91merr=\033[0m\n" +
+				"  \033[2m│\033[0m \033[91mdial\033[0m \033[2;91m(*fmt.wrapError)\033[0m\n" +
+				"  \033[2m│\033[0m   \033[91mfail\033[0m \033[2;91m(*errors.errorString)\033[0m",
+		},
+		{
+			Opts: &tint.Options{ExpandErrors: true, NoColor: true},
+			F: func(l *slog.Logger) {
+				l.Error("test", tint.Err(fmt.Errorf("wrap: %w", (*nilError)(nil))))
+			},
+			Want: "Nov 10 23:00:00.000 ERR test\n" +
+				"  err=\n" +
+				"  │ wrap (*fmt.wrapError)\n" +
+				"  │   <nil> (*tint_test.nilError)",
+		},
 	}
 )
 
@@ -656,6 +1047,7 @@ func TestHandler(t *testing.T) {
 	if now := time.Now(); !faketime.Equal(now) || now.Location().String() != "UTC" {
 		t.Skip(`run: TZ="" go test -tags=faketime`)
 	}
+	t.Setenv("COLORTERM", "truecolor") // don't downsample colors
 
 	for i, test := range handlerTests {
 		t.Run(strconv.Itoa(i), func(t *testing.T) {
@@ -783,57 +1175,919 @@ func TestAttr(t *testing.T) {
 	})
 }
 
-// TestClonedHandlersSynchronizeWriter tests that cloned handlers synchronize writer
-// writes with each other such that a logger can be shared among multiple goroutines.
-func TestClonedHandlersSynchronizeWriter(t *testing.T) {
-	// logSomething calls `With(...)` and uses the resulting logger to create and use a cloned handler.
-	logSomething := func(wg *sync.WaitGroup, logger *slog.Logger, loggerID int) {
-		defer wg.Done()
-		logger = logger.With("withLoggerID", loggerID)
-		logger.Info("test")
+func TestColorProfile(t *testing.T) {
+	tests := []struct {
+		Term, ColorTerm string
+		Want            string
+	}{
+		{Term: "xterm-256color", ColorTerm: "truecolor", Want: "\033[38;2;255;128;0mkey=val\033[0m"},
+		{Term: "", ColorTerm: "", Want: "\033[38;2;255;128;0mkey=val\033[0m"},
+		{Term: "xterm-256color", ColorTerm: "", Want: "\033[38;5;208mkey=val\033[0m"},
+		{Term: "screen", ColorTerm: "", Want: "\033[33mkey=val\033[0m"},
+		{Term: "dumb", ColorTerm: "", Want: "key=val"},
 	}
 
-	logger := slog.New(tint.NewHandler(&bytes.Buffer{}, &tint.Options{}))
+	for _, test := range tests {
+		t.Run(test.Term+"/"+test.ColorTerm, func(t *testing.T) {
+			t.Setenv("TERM", test.Term)
+			t.Setenv("COLORTERM", test.ColorTerm)
 
-	// start and wait for two goroutines
-	var wg sync.WaitGroup
-	wg.Add(2)
-	go logSomething(&wg, logger, 1)
-	go logSomething(&wg, logger, 2)
-	wg.Wait()
+			var buf bytes.Buffer
+			logger := slog.New(tint.NewHandler(&buf, &tint.Options{
+				ReplaceAttr: drop(slog.TimeKey, slog.LevelKey, slog.MessageKey),
+				Theme:       &tint.Theme{},
+			}))
+			logger.Info("test", tint.ColorAttr(tint.RGB(255, 128, 0), slog.String("key", "val")))
+
+			if got := strings.TrimSuffix(buf.String(), "\n"); test.Want != got {
+				t.Fatalf("(-want +got)\n- %q\n+ %q", test.Want, got)
+			}
+		})
+	}
 }
 
-// See https://github.com/golang/exp/blob/master/slog/benchmarks/benchmarks_test.go#L25
-//
-// Run e.g.:
-//
-//	go test -bench=. -count=10 | benchstat -col /h /dev/stdin
-func BenchmarkLogAttrs(b *testing.B) {
-	handler := []struct {
-		Name string
-		H    slog.Handler
+func TestColorMode(t *testing.T) {
+	tests := []struct {
+		Mode tint.ColorMode
+		Env  map[string]string
+		Want bool
 	}{
-		{"tint", tint.NewHandler(io.Discard, nil)},
-		{"text", slog.NewTextHandler(io.Discard, nil)},
-		{"json", slog.NewJSONHandler(io.Discard, nil)},
-		{"discard", new(discarder)},
+		{Mode: tint.ColorAlways, Want: true},
+		{Mode: tint.ColorNever, Want: false},
+		{Mode: tint.ColorAuto, Want: false},
+		{Mode: tint.ColorAuto, Env: map[string]string{"FORCE_COLOR": "1"}, Want: true},
+		{Mode: tint.ColorAuto, Env: map[string]string{"FORCE_COLOR": "0"}, Want: false},
+		{Mode: tint.ColorAuto, Env: map[string]string{"FORCE_COLOR": ""}, Want: false},
+		{Mode: tint.ColorAuto, Env: map[string]string{"CLICOLOR_FORCE": "1"}, Want: true},
+		{Mode: tint.ColorAuto, Env: map[string]string{"CLICOLOR_FORCE": "1", "NO_COLOR": "1"}, Want: false},
+		{Mode: tint.ColorAuto, Env: map[string]string{"FORCE_COLOR": "1", "NO_COLOR": "1"}, Want: false},
 	}
 
-	benchmarks := []struct {
-		Name string
-		F    func(*slog.Logger)
+	for i, test := range tests {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			for _, key := range []string{"NO_COLOR", "FORCE_COLOR", "CLICOLOR", "CLICOLOR_FORCE"} {
+				t.Setenv(key, "")
+				os.Unsetenv(key)
+			}
+			for key, val := range test.Env {
+				t.Setenv(key, val)
+			}
+
+			var buf bytes.Buffer
+			logger := slog.New(tint.NewHandler(&buf, &tint.Options{ColorMode: test.Mode}))
+			logger.Info("test")
+
+			if got := strings.ContainsRune(buf.String(), '\033'); test.Want != got {
+				t.Fatalf("want color: %t, got: %q", test.Want, buf.String())
+			}
+		})
+	}
+}
+
+func TestHexColor(t *testing.T) {
+	tests := []struct {
+		S       string
+		Want    tint.Color
+		WantErr bool
 	}{
-		{
-			"5 args",
-			func(logger *slog.Logger) {
-				logger.LogAttrs(context.TODO(), slog.LevelInfo, testMessage,
-					slog.String("string", testString),
-					slog.Int("status", testInt),
-					slog.Duration("duration", testDuration),
-					slog.Time("time", testTime),
-					slog.Any("error", errTest),
-				)
-			},
+		{S: "#ff8000", Want: tint.RGB(0xff, 0x80, 0x00)},
+		{S: "FF8000", Want: tint.RGB(0xff, 0x80, 0x00)},
+		{S: "#f80", Want: tint.RGB(0xff, 0x88, 0x00)},
+		{S: "", WantErr: true},
+		{S: "#ff80", WantErr: true},
+		{S: "#gg8000", WantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.S, func(t *testing.T) {
+			got, err := tint.HexColor(test.S)
+			if gotErr := err != nil; test.WantErr != gotErr {
+				t.Fatalf("want err: %t, got: %v", test.WantErr, err)
+			}
+			if test.Want != got {
+				t.Fatalf("want %#x, got %#x", test.Want, got)
+			}
+		})
+	}
+}
+
+func TestStackTrace(t *testing.T) {
+	opts := &tint.Options{
+		NoColor:            true,
+		StackTraceLevel:    slog.LevelError,
+		StackTracePrefixes: []string{"github.com/lmittmann/tint_test."},
+		ReplaceAttr:        drop(slog.TimeKey),
+	}
+
+	tests := []struct {
+		F    func(l *slog.Logger)
+		Want string // first frame, or "" if no stack trace is written
+	}{
+		{
+			F:    func(l *slog.Logger) { l.Info("test") },
+			Want: "",
+		},
+		{
+			F:    func(l *slog.Logger) { l.Error("test") },
+			Want: "tint_test.TestStackTrace.func2",
+		},
+		{
+			F:    func(l *slog.Logger) { l.Info("test", tint.Err(errTest)) },
+			Want: "tint_test.TestStackTrace.func3",
+		},
+		{
+			F:    func(l *slog.Logger) { l.With(tint.Err(errTest)).Info("test") },
+			Want: "tint_test.TestStackTrace.func4",
+		},
+		{
+			F:    func(l *slog.Logger) { l.Error("test", "err", newStackError()) },
+			Want: "tint_test.newStackError",
+		},
+		{
+			F:    func(l *slog.Logger) { l.Error("test", "err", fmt.Errorf("wrap: %w", newStackTracer())) },
+			Want: "tint_test.newStackTracer",
+		},
+	}
+
+	for i, test := range tests {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			var buf bytes.Buffer
+			test.F(slog.New(tint.NewHandler(&buf, opts)))
+
+			lines := strings.Split(buf.String(), "\n")
+			if test.Want == "" {
+				if len(lines) != 2 {
+					t.Fatalf("want no stack trace, got %q", buf.String())
+				}
+				return
+			}
+			if len(lines) < 4 || lines[1] != "  stack=" {
+				t.Fatalf("want stack trace, got %q", buf.String())
+			}
+			if prefix := "  │ " + test.Want + " "; !strings.HasPrefix(lines[2], prefix) || !strings.Contains(lines[2], "handler_test.go:") {
+				t.Fatalf("want first frame %q, got %q", prefix, lines[2])
+			}
+		})
+	}
+}
+
+func TestStackTraceStdlib(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(tint.NewHandler(&buf, &tint.Options{
+		StackTraceLevel: slog.LevelError,
+		ReplaceAttr:     drop(slog.TimeKey),
+	}))
+	logger.Error("test")
+
+	if want := "\033[2mtesting.tRunner\033[0m \033[2m"; !strings.Contains(buf.String(), want) {
+		t.Fatalf("want stdlib frame %q, got %q", want, buf.String())
+	}
+}
+
+func TestSourceFormat(t *testing.T) {
+	_, file, _, _ := runtime.Caller(0)
+	dir := filepath.Dir(file)
+
+	tests := []struct {
+		Opts *tint.Options
+		Want string // source without line
+	}{
+		{
+			Opts: &tint.Options{},
+			Want: filepath.Join(filepath.Base(dir), "handler_test.go"),
+		},
+		{
+			Opts: &tint.Options{SourceFormat: tint.SourceFormatLong},
+			Want: file,
+		},
+		{
+			Opts: &tint.Options{SourceFormat: tint.SourceFormatRelative},
+			Want: "handler_test.go",
+		},
+		{
+			Opts: &tint.Options{SourceFormat: tint.SourceFormatRelative, SourceRoot: filepath.Dir(dir)},
+			Want: filepath.Join(filepath.Base(dir), "handler_test.go"),
+		},
+		{
+			Opts: &tint.Options{SourceFormat: tint.SourceFormatRelative, SourceRoot: filepath.Dir(dir) + string(filepath.Separator)},
+			Want: filepath.Join(filepath.Base(dir), "handler_test.go"),
+		},
+		{
+			Opts: &tint.Options{SourceFormat: tint.SourceFormatRelative, SourceRoot: dir[:len(dir)-1]},
+			Want: filepath.Join(filepath.Base(dir), "handler_test.go"), // not within root
+		},
+		{
+			Opts: &tint.Options{SourceFormat: tint.SourceFormatBase},
+			Want: "handler_test.go",
+		},
+	}
+
+	for i, test := range tests {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			test.Opts.AddSource = true
+			test.Opts.NoColor = true
+			test.Opts.ReplaceAttr = drop(slog.TimeKey)
+
+			var buf bytes.Buffer
+			slog.New(tint.NewHandler(&buf, test.Opts)).Info("test")
+			_, _, line, _ := runtime.Caller(0)
+
+			want := fmt.Sprintf("INF %s:%d test\n", test.Want, line-1)
+			if got := buf.String(); want != got {
+				t.Fatalf("(-want +got)\n- %s\n+ %s", want, got)
+			}
+		})
+	}
+}
+
+func TestSourceFunction(t *testing.T) {
+	var buf bytes.Buffer
+	slog.New(tint.NewHandler(&buf, &tint.Options{
+		AddSource:      true,
+		NoColor:        true,
+		SourceFormat:   tint.SourceFormatBase,
+		SourceFunction: true,
+		ReplaceAttr:    drop(slog.TimeKey),
+	})).Info("test")
+	_, _, line, _ := runtime.Caller(0)
+
+	want := fmt.Sprintf("INF handler_test.go:%d tint_test.TestSourceFunction test\n", line-1)
+	if got := buf.String(); want != got {
+		t.Fatalf("(-want +got)\n- %s\n+ %s", want, got)
+	}
+}
+
+func TestSourceLink(t *testing.T) {
+	t.Setenv("COLORTERM", "truecolor")
+
+	var buf bytes.Buffer
+	slog.New(tint.NewHandler(&buf, &tint.Options{
+		AddSource:    true,
+		SourceFormat: tint.SourceFormatBase,
+		SourceLink:   "vscode://file/{path}:{line}",
+		SourceWidth:  24,
+		ReplaceAttr:  drop(slog.TimeKey),
+	})).Info("test")
+	_, file, line, _ := runtime.Caller(0)
+
+	src := fmt.Sprintf("handler_test.go:%d", line-1)
+	want := fmt.Sprintf("\033[92mINF\033[0m \033[2m\033]8;;vscode://file/%s:%d\033\\%s\033]8;;\033\\\033[0m%s test\n",
+		filepath.ToSlash(file), line-1, src, strings.Repeat(" ", 24-len(src)))
+	if got := buf.String(); want != got {
+		t.Fatalf("(-want +got)\n- %q\n+ %q", want, got)
+	}
+}
+
+func TestSourceSkip(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(tint.NewHandler(&buf, &tint.Options{
+		AddSource:    true,
+		NoColor:      true,
+		SourceFormat: tint.SourceFormatBase,
+		SourceSkip:   []string{"github.com/lmittmann/tint_test.logWrapped"},
+		ReplaceAttr:  drop(slog.TimeKey),
+	}))
+	logWrapped(logger, "test")
+	_, _, line, _ := runtime.Caller(0)
+
+	want := fmt.Sprintf("INF handler_test.go:%d test\n", line-1)
+	if got := buf.String(); want != got {
+		t.Fatalf("(-want +got)\n- %s\n+ %s", want, got)
+	}
+
+	// the source of FormatLogfmt and FormatJSON also skips wrappers
+	for format, src := range map[tint.Format]string{
+		tint.FormatLogfmt: "handler_test.go:%d",
+		tint.FormatJSON:   `handler_test.go","line":%d`,
+	} {
+		buf.Reset()
+		logger := slog.New(tint.NewHandler(&buf, &tint.Options{
+			AddSource:   true,
+			Format:      format,
+			SourceSkip:  []string{"github.com/lmittmann/tint_test.logWrapped"},
+			ReplaceAttr: drop(slog.TimeKey),
+		}))
+		logWrapped(logger, "test")
+		_, _, line, _ := runtime.Caller(0)
+
+		want := fmt.Sprintf(src, line-1)
+		if got := buf.String(); !strings.Contains(got, want) {
+			t.Fatalf("want source %q, got %q", want, got)
+		}
+	}
+}
+
+func TestTimeMode(t *testing.T) {
+	tests := []struct {
+		Mode tint.TimeMode
+		Want func(t1, t2 time.Time) string
+	}{
+		{
+			Mode: tint.TimeModeWall,
+			Want: func(t1, t2 time.Time) string {
+				return t1.Format(time.Kitchen) + " INF a\n" + t2.Format(time.Kitchen) + " INF b k=v\n"
+			},
+		},
+		{
+			Mode: tint.TimeModeElapsed,
+			Want: func(t1, t2 time.Time) string { return "+1.234s INF a\n+1.246s INF b k=v\n" },
+		},
+		{
+			Mode: tint.TimeModeDelta,
+			Want: func(t1, t2 time.Time) string { return "Δ1.234s INF a\nΔ12ms INF b k=v\n" },
+		},
+		{
+			Mode: tint.TimeModeWallDelta,
+			Want: func(t1, t2 time.Time) string {
+				return t1.Format(time.Kitchen) + " Δ1.234s INF a\n" + t2.Format(time.Kitchen) + " Δ12ms INF b k=v\n"
+			},
+		},
+	}
+
+	for i, test := range tests {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			var buf bytes.Buffer
+			h := tint.NewHandler(&buf, &tint.Options{
+				NoColor:    true,
+				TimeFormat: time.Kitchen,
+				TimeMode:   test.Mode,
+			})
+			t1 := time.Now().Add(1234 * time.Millisecond)
+			t2 := t1.Add(12*time.Millisecond + 500*time.Microsecond)
+
+			// the clock is shared with clones of the handler
+			h.Handle(context.Background(), slog.NewRecord(t1, slog.LevelInfo, "a", 0))
+			h.WithAttrs([]slog.Attr{slog.String("k", "v")}).Handle(context.Background(), slog.NewRecord(t2, slog.LevelInfo, "b", 0))
+
+			if want, got := test.Want(t1, t2), buf.String(); want != got {
+				t.Fatalf("(-want +got)\n- %s\n+ %s", want, got)
+			}
+		})
+	}
+}
+
+func TestTimeLocation(t *testing.T) {
+	tm := time.Date(2024, 1, 2, 3, 4, 5, 6e6, time.FixedZone("CET", 1*60*60))
+
+	tests := []struct {
+		Loc  *time.Location
+		Want string
+	}{
+		{
+			Loc:  nil,
+			Want: "2024-01-02T03:04:05+01:00 INF test at=2024-01-02T03:04:05.006+01:00\n",
+		},
+		{
+			Loc:  time.UTC,
+			Want: "2024-01-02T02:04:05Z INF test at=2024-01-02T02:04:05.006Z\n",
+		},
+		{
+			Loc:  time.FixedZone("EST", -5*60*60),
+			Want: "2024-01-01T21:04:05-05:00 INF test at=2024-01-01T21:04:05.006-05:00\n",
+		},
+	}
+
+	for i, test := range tests {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			var buf bytes.Buffer
+			h := tint.NewHandler(&buf, &tint.Options{
+				NoColor:      true,
+				TimeFormat:   time.RFC3339,
+				TimeLocation: test.Loc,
+			})
+			r := slog.NewRecord(tm, slog.LevelInfo, "test", 0)
+			r.AddAttrs(slog.Time("at", tm))
+			h.Handle(context.Background(), r)
+
+			if got := buf.String(); test.Want != got {
+				t.Fatalf("(-want +got)\n- %s\n+ %s", test.Want, got)
+			}
+		})
+	}
+}
+
+func TestDurationFormat(t *testing.T) {
+	durs := []time.Duration{
+		1234567 * time.Microsecond,
+		350500 * time.Microsecond,
+		1500 * time.Nanosecond,
+		125400 * time.Millisecond,
+		90 * time.Minute,
+	}
+
+	tests := []struct {
+		Opts *tint.Options
+		Want string
+	}{
+		{
+			Opts: &tint.Options{},
+			Want: `INF test d=1.234567s d=350.5ms d=1.5µs d=2m5.4s d=1h30m0s`,
+		},
+		{
+			Opts: &tint.Options{DurationFormat: tint.DurationFormatHuman},
+			Want: `INF test d=1.2s d=351ms d=1.5µs d=2m5s d=1h30m`,
+		},
+		{
+			Opts: &tint.Options{DurationFormat: tint.DurationFormatMillis},
+			Want: `INF test d=1234.567ms d=350.5ms d=0.0015ms d=125400ms d=5400000ms`,
+		},
+		{
+			Opts: &tint.Options{DurationFormat: tint.DurationFormatSeconds},
+			Want: `INF test d=1.234567s d=0.3505s d=0.0000015s d=125.4s d=5400s`,
+		},
+		{
+			Opts: &tint.Options{AttrTimeFormat: time.Kitchen},
+			Want: `INF test t=3:04AM`,
+		},
+	}
+
+	for i, test := range tests {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			test.Opts.NoColor = true
+			test.Opts.ReplaceAttr = drop(slog.TimeKey)
+
+			var buf bytes.Buffer
+			logger := slog.New(tint.NewHandler(&buf, test.Opts))
+			if test.Opts.AttrTimeFormat != "" {
+				logger.Info("test", "t", time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC))
+			} else {
+				args := make([]any, 0, 2*len(durs))
+				for _, d := range durs {
+					args = append(args, "d", d)
+				}
+				logger.Info("test", args...)
+			}
+
+			if got := strings.TrimSuffix(buf.String(), "\n"); test.Want != got {
+				t.Fatalf("(-want +got)\n- %s\n+ %s", test.Want, got)
+			}
+		})
+	}
+}
+
+func TestDurationFormatHuman(t *testing.T) {
+	tests := []struct {
+		D    time.Duration
+		Want string
+	}{
+		{D: 0, Want: "0ns"},
+		{D: 999 * time.Nanosecond, Want: "999ns"},
+		{D: 9960 * time.Nanosecond, Want: "10µs"},
+		{D: 123 * time.Millisecond, Want: "123ms"},
+		{D: 999960 * time.Microsecond, Want: "1s"},
+		{D: 59960 * time.Millisecond, Want: "1m"},
+		{D: -1500 * time.Millisecond, Want: "-1.5s"},
+		{D: time.Hour + 10*time.Minute + 20*time.Second, Want: "1h10m"},
+		{D: 59*time.Minute + 59500*time.Millisecond, Want: "1h"},
+		{D: 100 * time.Hour, Want: "100h"},
+		{D: 2 * time.Minute, Want: "2m"},
+		{D: math.MinInt64, Want: "-2562047h47m"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Want, func(t *testing.T) {
+			var buf bytes.Buffer
+			slog.New(tint.NewHandler(&buf, &tint.Options{
+				NoColor:        true,
+				DurationFormat: tint.DurationFormatHuman,
+				ReplaceAttr:    drop(slog.TimeKey),
+			})).Info("test", "d", test.D)
+
+			if want, got := "INF test d="+test.Want+"\n", buf.String(); want != got {
+				t.Fatalf("(-want +got)\n- %s\n+ %s", want, got)
+			}
+		})
+	}
+}
+
+func TestDurationStyles(t *testing.T) {
+	t.Setenv("COLORTERM", "truecolor")
+
+	yellow := tint.DurationStyle{Duration: 500 * time.Millisecond, Style: tint.Style{Foreground: tint.ANSIColor(11)}}
+	red := tint.DurationStyle{Duration: 2 * time.Second, Style: tint.Style{Foreground: tint.ANSIColor(9)}}
+
+	for i, styles := range [][]tint.DurationStyle{{yellow, red}, {red, yellow}} {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			var buf bytes.Buffer
+			logger := slog.New(tint.NewHandler(&buf, &tint.Options{
+				DurationStyles: styles,
+				ReplaceAttr:    drop(slog.TimeKey),
+			}))
+			logger.Info("test", "a", 100*time.Millisecond, "b", time.Second, "c", 3*time.Second)
+
+			want := "\033[92mINF\033[0m test \033[2ma=\033[0m100ms \033[2mb=\033[0m\033[93m1s\033[0m \033[2mc=\033[0m\033[91m3s\033[0m\n"
+			if got := buf.String(); want != got {
+				t.Fatalf("(-want +got)\n- %q\n+ %q", want, got)
+			}
+		})
+	}
+}
+
+func TestParseLevelSpec(t *testing.T) {
+	tests := []struct {
+		S       string
+		Want    tint.LevelSpec
+		WantErr bool
+	}{
+		{S: "", Want: nil},
+		{S: "info", Want: tint.LevelSpec{{Level: slog.LevelInfo}}},
+		{
+			S: "info, db=debug,http.client=WARN+2",
+			Want: tint.LevelSpec{
+				{Level: slog.LevelInfo},
+				{Module: "db", Level: slog.LevelDebug},
+				{Module: "http.client", Level: slog.LevelWarn + 2},
+			},
+		},
+		{S: "db=verbose", WantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.S, func(t *testing.T) {
+			got, err := tint.ParseLevelSpec(test.S)
+			if gotErr := err != nil; test.WantErr != gotErr {
+				t.Fatalf("want err: %t, got: %v", test.WantErr, err)
+			}
+			if !slices.Equal(test.Want, got) {
+				t.Fatalf("want %v, got %v", test.Want, got)
+			}
+		})
+	}
+}
+
+func TestLevelSpec(t *testing.T) {
+	tests := []struct {
+		Spec   string
+		Format tint.Format
+		F      func(l *slog.Logger)
+		Want   string
+	}{
+		{
+			Spec: "warn,db=debug",
+			F: func(l *slog.Logger) {
+				l.Info("a")
+				l.WithGroup("db").Debug("b")
+				l.WithGroup("db").WithGroup("tx").Debug("c")
+				l.WithGroup("dbx").Info("d")
+			},
+			Want: "DBG b\nDBG c\n",
+		},
+		{
+			Spec: "error,tint_test=debug",
+			F: func(l *slog.Logger) {
+				l.Debug("a")
+			},
+			Want: "DBG a\n",
+		},
+		{
+			Spec: "error,github.com/lmittmann=debug,other=debug",
+			F: func(l *slog.Logger) {
+				l.Debug("a")
+				l.WithGroup("other").Debug("b")
+			},
+			Want: "DBG a\nDBG b\n",
+		},
+		{
+			Spec: "debug,http=info,http.client=error",
+			F: func(l *slog.Logger) {
+				l.Debug("a")
+				l.WithGroup("http").Debug("b")
+				l.WithGroup("http").Info("c")
+				l.WithGroup("http").WithGroup("client").Warn("d")
+			},
+			Want: "DBG a\nINF c\n",
+		},
+		{
+			Spec:   "warn,db=debug",
+			Format: tint.FormatLogfmt,
+			F: func(l *slog.Logger) {
+				l.Info("a")
+				l.WithGroup("db").Debug("b")
+			},
+			Want: "level=DBG msg=b\n",
+		},
+		{
+			Spec:   "warn,db=debug",
+			Format: tint.FormatJSON,
+			F: func(l *slog.Logger) {
+				l.Info("a")
+				l.WithGroup("db").Debug("b")
+			},
+			Want: `{"level":"DBG","msg":"b"}` + "\n",
+		},
+	}
+
+	for i, test := range tests {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			spec, err := tint.ParseLevelSpec(test.Spec)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := spec.String(); test.Spec != got {
+				t.Fatalf("want spec %q, got %q", test.Spec, got)
+			}
+
+			var buf bytes.Buffer
+			test.F(slog.New(tint.NewHandler(&buf, &tint.Options{
+				NoColor:     true,
+				Format:      test.Format,
+				LevelSpec:   spec,
+				ReplaceAttr: drop(slog.TimeKey),
+			})))
+
+			if got := buf.String(); test.Want != got {
+				t.Fatalf("(-want +got)\n- %s\n+ %s", test.Want, got)
+			}
+		})
+	}
+}
+
+func TestZeroHandler(t *testing.T) {
+	h := &tint.Handler{}
+	h.SetLevel(slog.LevelDebug)
+
+	logger := slog.New(h)
+	logger.Error("test", "k", "v")
+	logger.With("k", "v").WithGroup("g").Error("test")
+	if h.Enabled(context.Background(), slog.LevelError) {
+		t.Fatal("zero Handler is enabled")
+	}
+}
+
+func TestHandlerSetters(t *testing.T) {
+	t.Setenv("COLORTERM", "truecolor")
+
+	var buf bytes.Buffer
+	h := tint.NewHandler(&buf, &tint.Options{TimeFormat: "2006"}).(*tint.Handler)
+	logger := slog.New(h).With("k", "v").WithGroup("g")
+	now := time.Now()
+
+	logger.Debug("a")
+	h.SetLevel(slog.LevelDebug)
+	logger.Debug("b")
+	h.SetColorMode(tint.ColorNever)
+	logger.Debug("c", "x", 1)
+
+	// setters of derived handlers apply to the parent handler
+	logger.Handler().(*tint.Handler).SetTimeFormat("06")
+	logger.Handler().(*tint.Handler).SetColorMode(tint.ColorAlways)
+	logger.Handler().(*tint.Handler).SetTheme(tint.LightTheme())
+	slog.New(h).Info("d")
+
+	want := "\033[2m" + now.Format("2006") + "\033[0m DBG b \033[2mk=\033[0mv\n" +
+		now.Format("2006") + " DBG c k=v g.x=1\n" +
+		"\033[38;5;240m" + now.Format("06") + "\033[0m \033[32mINF\033[0m d\n"
+	if got := buf.String(); want != got {
+		t.Fatalf("(-want +got)\n- %q\n+ %q", want, got)
+	}
+}
+
+func TestOptionsUnmarshalText(t *testing.T) {
+	tests := []struct {
+		Text    string
+		Want    func(o *tint.Options) bool
+		WantErr bool
+	}{
+		{
+			Text: "level=debug",
+			Want: func(o *tint.Options) bool { return o.Level == slog.LevelDebug && o.LevelSpec == nil },
+		},
+		{
+			Text: "level=info,db=debug; source",
+			Want: func(o *tint.Options) bool { return o.LevelSpec.String() == "info,db=debug" && o.AddSource },
+		},
+		{
+			Text: "time=15:04:05;tz=UTC;color=never;theme=light",
+			Want: func(o *tint.Options) bool {
+				return o.TimeFormat == "15:04:05" && o.TimeLocation == time.UTC &&
+					o.ColorMode == tint.ColorNever && o.Theme.Info == tint.LightTheme().Info
+			},
+		},
+		{
+			Text: "format=json",
+			Want: func(o *tint.Options) bool { return o.Format == tint.FormatJSON },
+		},
+		{
+			Text: "time=delta",
+			Want: func(o *tint.Options) bool { return o.TimeMode == tint.TimeModeDelta },
+		},
+		{Text: "level=verbose", WantErr: true},
+		{Text: "color=sometimes", WantErr: true},
+		{Text: "source=maybe", WantErr: true},
+		{Text: "unknown=1", WantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Text, func(t *testing.T) {
+			var opts tint.Options
+			err := opts.UnmarshalText([]byte(test.Text))
+			if gotErr := err != nil; test.WantErr != gotErr {
+				t.Fatalf("want err: %t, got: %v", test.WantErr, err)
+			}
+			if test.Want != nil && !test.Want(&opts) {
+				t.Fatalf("unexpected options: %+v", opts)
+			}
+		})
+	}
+}
+
+func TestOptionsFromEnv(t *testing.T) {
+	t.Setenv("NO_COLOR", "1")
+	t.Setenv("APP_LOG_LEVEL", "warn")
+	t.Setenv("APP_LOG_SOURCE", "true")
+	t.Setenv("APP_LOG_TIME", "elapsed")
+	t.Setenv("APP_LOG_TZ", "")
+
+	opts, err := tint.OptionsFromEnv("APP_LOG_")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !opts.NoColor || opts.Level != slog.LevelWarn || !opts.AddSource || opts.TimeMode != tint.TimeModeElapsed || opts.TimeLocation != nil {
+		t.Fatalf("unexpected options: %+v", opts)
+	}
+
+	t.Setenv("APP_LOG_COLOR", "sometimes")
+	if _, err := tint.OptionsFromEnv("APP_LOG_"); err == nil {
+		t.Fatal("want error")
+	}
+}
+
+func TestRegisterFlags(t *testing.T) {
+	var opts tint.Options
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	opts.RegisterFlags(fs, "log-")
+
+	if err := fs.Parse([]string{"-log-level=debug", "-log-source", "-log-color", "always"}); err != nil {
+		t.Fatal(err)
+	}
+	if opts.Level != slog.LevelDebug || !opts.AddSource || opts.ColorMode != tint.ColorAlways {
+		t.Fatalf("unexpected options: %+v", opts)
+	}
+}
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		Format      tint.Format
+		LevelFormat tint.LevelFormat
+		Want        string
+	}{
+		{
+			Format: tint.FormatText,
+			Want:   "TRC+1 a k=v\nINF b k=v g.err=fail g.n=1\n",
+		},
+		{
+			Format: tint.FormatLogfmt,
+			Want:   "level=TRC+1 msg=a k=v\nlevel=INF msg=b k=v g.err=fail g.n=1\n",
+		},
+		{
+			Format: tint.FormatJSON,
+			Want:   `{"level":"TRC+1","msg":"a","k":"v"}` + "\n" + `{"level":"INF","msg":"b","k":"v","g":{"err":"fail","n":1}}` + "\n",
+		},
+		{
+			Format:      tint.FormatJSON,
+			LevelFormat: tint.LevelFormatLong,
+			Want:        `{"level":"TRC+1","msg":"a","k":"v"}` + "\n" + `{"level":"INFO","msg":"b","k":"v","g":{"err":"fail","n":1}}` + "\n",
+		},
+	}
+
+	for i, test := range tests {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			var buf bytes.Buffer
+			h := tint.NewHandler(&buf, &tint.Options{
+				Format:      test.Format,
+				LevelFormat: test.LevelFormat,
+				NoColor:     true,
+				Levels:      []tint.Level{{Level: slog.LevelDebug - 4, Label: "TRC"}},
+				ReplaceAttr: drop(slog.TimeKey),
+			}).(*tint.Handler)
+			logger := slog.New(h).With(tint.Attr(9, slog.String("k", "v")))
+
+			logger.Log(context.Background(), slog.LevelDebug-4, "dropped")
+			h.SetLevel(slog.LevelDebug - 4)
+			logger.Log(context.Background(), slog.LevelDebug-3, "a")
+			logger.WithGroup("g").Info("b", tint.Err(errors.New("fail")), "n", 1)
+
+			if got := buf.String(); test.Want != got {
+				t.Fatalf("(-want +got)\n- %s\n+ %s", test.Want, got)
+			}
+		})
+	}
+}
+
+func TestHandlerSetLevelWithLevelSpec(t *testing.T) {
+	spec, err := tint.ParseLevelSpec("info,db=warn")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	h := tint.NewHandler(&buf, &tint.Options{
+		NoColor:     true,
+		LevelSpec:   spec,
+		ReplaceAttr: drop(slog.TimeKey),
+	}).(*tint.Handler)
+	logger := slog.New(h)
+
+	logger.Debug("a")
+	h.SetLevel(slog.LevelDebug) // replaces the default level of the spec
+	logger.Debug("b")
+	logger.WithGroup("db").Info("c")
+	logger.WithGroup("db").Warn("d")
+
+	if want, got := "DBG b\nWRN d\n", buf.String(); want != got {
+		t.Fatalf("(-want +got)\n- %s\n+ %s", want, got)
+	}
+}
+
+func TestFormatTime(t *testing.T) {
+	tm := time.Date(2024, 1, 2, 3, 4, 5, 0, time.FixedZone("CET", 1*60*60))
+
+	tests := []struct {
+		Format tint.Format
+		Want   string
+	}{
+		{
+			Format: tint.FormatLogfmt,
+			Want:   `time=02:04 level=INF msg=test at=2:04AM d=1.2s` + "\n",
+		},
+		{
+			Format: tint.FormatJSON,
+			Want:   `{"time":"2024-01-02T02:04:05Z","level":"INF","msg":"test","at":"2024-01-02T02:04:05Z","d":1234000000}` + "\n",
+		},
+	}
+
+	for i, test := range tests {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			var buf bytes.Buffer
+			h := tint.NewHandler(&buf, &tint.Options{
+				Format:         test.Format,
+				TimeFormat:     "15:04",
+				TimeLocation:   time.UTC,
+				AttrTimeFormat: time.Kitchen,
+				DurationFormat: tint.DurationFormatHuman,
+			})
+			r := slog.NewRecord(tm, slog.LevelInfo, "test", 0)
+			r.AddAttrs(slog.Time("at", tm), slog.Duration("d", 1234*time.Millisecond))
+			h.Handle(context.Background(), r)
+
+			if got := buf.String(); test.Want != got {
+				t.Fatalf("(-want +got)\n- %s\n+ %s", test.Want, got)
+			}
+		})
+	}
+}
+
+// TestClonedHandlersSynchronizeWriter tests that cloned handlers synchronize writer
+// writes with each other such that a logger can be shared among multiple goroutines.
+func TestClonedHandlersSynchronizeWriter(t *testing.T) {
+	// logSomething calls `With(...)` and uses the resulting logger to create and use a cloned handler.
+	logSomething := func(wg *sync.WaitGroup, logger *slog.Logger, loggerID int) {
+		defer wg.Done()
+		logger = logger.With("withLoggerID", loggerID)
+		logger.Info("test")
+	}
+
+	logger := slog.New(tint.NewHandler(&bytes.Buffer{}, &tint.Options{}))
+
+	// start and wait for two goroutines
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go logSomething(&wg, logger, 1)
+	go logSomething(&wg, logger, 2)
+	wg.Wait()
+}
+
+// See https://github.com/golang/exp/blob/master/slog/benchmarks/benchmarks_test.go#L25
+//
+// Run e.g.:
+//
+//	go test -bench=. -count=10 | benchstat -col /h /dev/stdin
+func BenchmarkLogAttrs(b *testing.B) {
+	handler := []struct {
+		Name string
+		H    slog.Handler
+	}{
+		{"tint", tint.NewHandler(io.Discard, nil)},
+		{"text", slog.NewTextHandler(io.Discard, nil)},
+		{"json", slog.NewJSONHandler(io.Discard, nil)},
+		{"discard", new(discarder)},
+	}
+
+	benchmarks := []struct {
+		Name string
+		F    func(*slog.Logger)
+	}{
+		{
+			"5 args",
+			func(logger *slog.Logger) {
+				logger.LogAttrs(context.TODO(), slog.LevelInfo, testMessage,
+					slog.String("string", testString),
+					slog.Int("status", testInt),
+					slog.Duration("duration", testDuration),
+					slog.Time("time", testTime),
+					slog.Any("error", errTest),
+				)
+			},
 		},
 		{
 			"5 args custom level",
@@ -952,6 +2206,16 @@ func (*discarder) Handle(context.Context, slog.Record) error  { return nil }
 func (d *discarder) WithAttrs(attrs []slog.Attr) slog.Handler { return d }
 func (d *discarder) WithGroup(name string) slog.Handler       { return d }
 
+// failingMarshaler is a json.Marshaler that always fails.
+// nilError is an error whose Error method panics on a nil pointer.
+type nilError struct{ msg string }
+
+func (e *nilError) Error() string { return e.msg }
+
+type failingMarshaler struct{ A int }
+
+func (failingMarshaler) MarshalJSON() ([]byte, error) { return nil, errTest }
+
 var (
 	testMessage  = "Test logging, but use a somewhat realistic message length."
 	testTime     = time.Date(2022, time.May, 1, 0, 0, 0, 0, time.UTC)
@@ -960,3 +2224,38 @@ var (
 	testDuration = 23 * time.Second
 	errTest      = errors.New("fail")
 )
+
+// logWrapped wraps a logger like a logging helper.
+func logWrapped(logger *slog.Logger, msg string) {
+	logger.Info(msg)
+}
+
+// stackError is an error with a stack trace provided by a Callers method.
+type stackError struct{ pcs []uintptr }
+
+func newStackError() error {
+	pcs := make([]uintptr, 32)
+	return &stackError{pcs[:runtime.Callers(1, pcs)]}
+}
+
+func (e *stackError) Error() string      { return "stack error" }
+func (e *stackError) Callers() []uintptr { return e.pcs }
+
+// stackTracer is an error with a stack trace provided by a StackTrace method,
+// as in github.com/pkg/errors.
+type stackTracer struct{ frames []frame }
+
+type frame uintptr
+
+func newStackTracer() error {
+	pcs := make([]uintptr, 32)
+	pcs = pcs[:runtime.Callers(1, pcs)]
+	frames := make([]frame, len(pcs))
+	for i, pc := range pcs {
+		frames[i] = frame(pc)
+	}
+	return &stackTracer{frames}
+}
+
+func (e *stackTracer) Error() string       { return "stack tracer" }
+func (e *stackTracer) StackTrace() []frame { return e.frames }
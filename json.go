@@ -0,0 +1,64 @@
+package tint
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+)
+
+// appendJSON writes the JSON data in compact form with syntax highlighting. The
+// style of the value is given by style and takes precedence over the
+// highlighting. It reports false and writes nothing if data is not valid JSON.
+func (h *Handler) appendJSON(buf *buffer, data []byte, style Style) bool {
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, data); err != nil {
+		return false
+	}
+	data = compact.Bytes()
+
+	theme := h.theme
+	token := func(tok []byte, tokStyle Style) {
+		tokStyle = tokStyle.with(style)
+		h.appendStyle(buf, style, tokStyle)
+		buf.Write(tok)
+		h.appendStyle(buf, tokStyle, style)
+	}
+
+	for i := 0; i < len(data); {
+		switch c := data[i]; {
+		case c == '"':
+			end := i + 1
+			for ; data[end] != '"'; end++ {
+				if data[end] == '\\' {
+					end++
+				}
+			}
+			end++
+
+			if end < len(data) && data[end] == ':' {
+				token(data[i:end], theme.JSONKey)
+			} else {
+				token(data[i:end], theme.JSONString)
+			}
+			i = end
+		case c == '-' || ('0' <= c && c <= '9'):
+			end := i + 1
+			for end < len(data) && strings.IndexByte("0123456789+-.eE", data[end]) >= 0 {
+				end++
+			}
+			token(data[i:end], theme.JSONNumber)
+			i = end
+		case 'a' <= c && c <= 'z':
+			end := i + 1
+			for end < len(data) && 'a' <= data[end] && data[end] <= 'z' {
+				end++
+			}
+			token(data[i:end], theme.JSONLiteral)
+			i = end
+		default:
+			buf.WriteByte(c)
+			i++
+		}
+	}
+	return true
+}
@@ -0,0 +1,118 @@
+package tint
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+)
+
+// ModuleLevel is the minimum level of a module in a [LevelSpec].
+type ModuleLevel struct {
+	// Name of the module, or "" for the default level. A module matches
+	// handlers with this group, e.g. "http.client" for
+	// logger.WithGroup("http").WithGroup("client"), and callers in packages
+	// with this import path, path prefix or last path element, e.g. "db" for
+	// "github.com/acme/app/db".
+	Module string
+
+	// Minimum level of the module
+	Level slog.Level
+}
+
+// LevelSpec sets the minimum level per module. If a record matches multiple
+// modules, the longest module takes precedence.
+type LevelSpec []ModuleLevel
+
+// ParseLevelSpec parses a comma-separated level spec of levels and
+// module=level pairs, e.g. "info,db=debug,http.client=warn".
+func ParseLevelSpec(s string) (LevelSpec, error) {
+	var spec LevelSpec
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		module, level, ok := strings.Cut(entry, "=")
+		if !ok {
+			module, level = "", entry
+		}
+		var l slog.Level
+		if err := l.UnmarshalText([]byte(strings.TrimSpace(level))); err != nil {
+			return nil, fmt.Errorf("tint: invalid level spec %q: %w", entry, err)
+		}
+		spec = append(spec, ModuleLevel{Module: strings.TrimSpace(module), Level: l})
+	}
+	return spec, nil
+}
+
+// String returns the level spec in the format accepted by [ParseLevelSpec].
+func (s LevelSpec) String() string {
+	var b strings.Builder
+	for i, m := range s {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		if m.Module != "" {
+			b.WriteString(m.Module)
+			b.WriteByte('=')
+		}
+		b.WriteString(strings.ToLower(m.Level.String()))
+	}
+	return b.String()
+}
+
+// minLevel returns the lowest level that may be enabled for a record of the
+// handler. The exact level depends on the caller and is given by
+// [Handler.levelFor].
+func (h *Handler) minLevel() slog.Level {
+	best, level := h.groupLevel()
+	for _, m := range h.opts.LevelSpec {
+		if len(m.Module) > len(best) {
+			level = min(level, m.Level)
+		}
+	}
+	return level
+}
+
+// groupLevel returns the longest module of Options.LevelSpec that matches the
+// groups of the handler and its level, or the default level.
+func (h *Handler) groupLevel() (module string, level slog.Level) {
+	level = h.opts.Level.Level()
+	groups := strings.TrimSuffix(h.groupPrefix, ".")
+	best := -1
+	for _, m := range h.opts.LevelSpec {
+		if len(m.Module) > best && (m.Module == "" || matchGroups(m.Module, groups)) {
+			module, level, best = m.Module, m.Level, len(m.Module)
+		}
+	}
+	return module, level
+}
+
+// levelFor returns the minimum level of a record of the handler logged at pc.
+func (h *Handler) levelFor(pc uintptr) slog.Level {
+	module, level := h.groupLevel()
+	if pc == 0 {
+		return level
+	}
+
+	pkg := funcPackage(h.callerFrame(pc).Function)
+	for _, m := range h.opts.LevelSpec {
+		if len(m.Module) > len(module) && matchPackage(m.Module, pkg) {
+			module, level = m.Module, m.Level
+		}
+	}
+	return level
+}
+
+// matchGroups reports whether module matches the groups joined by ".".
+func matchGroups(module, groups string) bool {
+	return groups == module || strings.HasPrefix(groups, module+".")
+}
+
+// matchPackage reports whether module matches the package import path.
+func matchPackage(module, pkg string) bool {
+	return pkg == module ||
+		strings.HasPrefix(pkg, module+"/") ||
+		strings.HasSuffix(pkg, "/"+module)
+}
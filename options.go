@@ -0,0 +1,222 @@
+package tint
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var errUnknownOption = errors.New("unknown option")
+
+// option is an option that can be set by name in a spec string, an environment
+// variable or a flag.
+type option struct {
+	name  string
+	usage string
+	set   func(o *Options, s string) error
+}
+
+// options are the options that can be set by name.
+var options = []option{
+	{
+		name:  "level",
+		usage: `minimum level or level spec, e.g. "info,db=debug"`,
+		set: func(o *Options, s string) error {
+			spec, err := ParseLevelSpec(s)
+			if err != nil {
+				return err
+			}
+			if len(spec) == 1 && spec[0].Module == "" {
+				o.Level, o.LevelSpec = spec[0].Level, nil
+			} else {
+				o.LevelSpec = spec
+			}
+			return nil
+		},
+	},
+	{
+		name:  "format",
+		usage: `output format, "text", "logfmt" or "json"`,
+		set: func(o *Options, s string) error {
+			switch strings.ToLower(s) {
+			case "text":
+				o.Format = FormatText
+			case "logfmt":
+				o.Format = FormatLogfmt
+			case "json":
+				o.Format = FormatJSON
+			default:
+				return fmt.Errorf("invalid format %q", s)
+			}
+			return nil
+		},
+	},
+	{
+		name:  "time",
+		usage: `time format, or "elapsed", "delta" or "walldelta"`,
+		set: func(o *Options, s string) error {
+			switch s {
+			case "elapsed":
+				o.TimeMode = TimeModeElapsed
+			case "delta":
+				o.TimeMode = TimeModeDelta
+			case "walldelta":
+				o.TimeMode = TimeModeWallDelta
+			default:
+				o.TimeMode, o.TimeFormat = TimeModeWall, s
+			}
+			return nil
+		},
+	},
+	{
+		name:  "tz",
+		usage: `time zone, e.g. "UTC" or "Europe/Berlin"`,
+		set: func(o *Options, s string) (err error) {
+			if s == "" {
+				o.TimeLocation = nil // unset, unlike time.LoadLocation("") for UTC
+				return nil
+			}
+			o.TimeLocation, err = time.LoadLocation(s)
+			return err
+		},
+	},
+	{
+		name:  "color",
+		usage: `color mode, "auto", "always" or "never"`,
+		set: func(o *Options, s string) error {
+			switch strings.ToLower(s) {
+			case "auto":
+				o.ColorMode = ColorAuto
+			case "always":
+				o.ColorMode = ColorAlways
+			case "never":
+				o.ColorMode = ColorNever
+			default:
+				return fmt.Errorf("invalid color mode %q", s)
+			}
+			return nil
+		},
+	},
+	{
+		name:  "source",
+		usage: "write the source code location",
+		set: func(o *Options, s string) (err error) {
+			o.AddSource, err = strconv.ParseBool(s)
+			return err
+		},
+	},
+	{
+		name:  "theme",
+		usage: `theme, "dark" or "light"`,
+		set: func(o *Options, s string) error {
+			switch strings.ToLower(s) {
+			case "dark":
+				o.Theme = DarkTheme()
+			case "light":
+				o.Theme = LightTheme()
+			default:
+				return fmt.Errorf("invalid theme %q", s)
+			}
+			return nil
+		},
+	},
+}
+
+// UnmarshalText sets the options given in a semicolon-separated spec of
+// name=value pairs, e.g. "level=info,db=debug;time=15:04:05;color=auto". A
+// name without a value is set to "true". Options that are not given in the
+// spec are left unchanged.
+//
+// The names are "level", "format", "time", "tz", "color", "source" and "theme". See
+// [Options.RegisterFlags] for their values.
+func (o *Options) UnmarshalText(text []byte) error {
+	for _, entry := range strings.Split(string(text), ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		name, val, ok := strings.Cut(entry, "=")
+		if !ok {
+			val = "true"
+		}
+		if err := o.set(strings.TrimSpace(name), val); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// set sets the option with the given name.
+func (o *Options) set(name, val string) error {
+	for _, opt := range options {
+		if opt.name == name {
+			if err := opt.set(o, val); err != nil {
+				return fmt.Errorf("tint: invalid option %s=%q: %w", name, val, err)
+			}
+			return nil
+		}
+	}
+	return fmt.Errorf("tint: %w %q", errUnknownOption, name)
+}
+
+// OptionsFromEnv returns options that are set by the environment variables
+// with the given prefix followed by the upper-case option name, e.g.
+// LOG_LEVEL, LOG_FORMAT, LOG_TIME, LOG_TZ, LOG_COLOR, LOG_SOURCE and LOG_THEME for the
+// prefix "LOG_". Colors are disabled if NO_COLOR is set. See
+// [Options.RegisterFlags] for the values of the options.
+func OptionsFromEnv(prefix string) (*Options, error) {
+	o := &Options{}
+	for _, opt := range options {
+		if val, ok := os.LookupEnv(prefix + strings.ToUpper(opt.name)); ok {
+			if err := o.set(opt.name, val); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if os.Getenv("NO_COLOR") != "" {
+		o.NoColor = true
+	}
+	return o, nil
+}
+
+// RegisterFlags registers a flag for each option on the FlagSet, with the
+// given prefix followed by the option name, e.g. -log-level for the prefix
+// "log-". The flags set the options when they are parsed:
+//
+//   - level: minimum level or level spec, e.g. "info,db=debug"
+//   - format: output format, "text", "logfmt" or "json"
+//   - time: time format, or "elapsed", "delta" or "walldelta"
+//   - tz: time zone, e.g. "UTC" or "Europe/Berlin"
+//   - color: color mode, "auto", "always" or "never"
+//   - source: write the source code location
+//   - theme: theme, "dark" or "light"
+func (o *Options) RegisterFlags(fs *flag.FlagSet, prefix string) {
+	for _, opt := range options {
+		fs.Var(&optionFlag{o: o, name: opt.name}, prefix+opt.name, opt.usage)
+	}
+}
+
+// optionFlag is a [flag.Value] that sets an option.
+type optionFlag struct {
+	o    *Options
+	name string
+	val  string
+}
+
+func (f *optionFlag) String() string { return f.val }
+
+func (f *optionFlag) Set(val string) error {
+	if err := f.o.set(f.name, val); err != nil {
+		return err
+	}
+	f.val = val
+	return nil
+}
+
+// IsBoolFlag allows the source flag to be set without a value.
+func (f *optionFlag) IsBoolFlag() bool { return f.name == "source" }
@@ -0,0 +1,160 @@
+package tint
+
+import (
+	"cmp"
+	"encoding"
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+)
+
+// isPretty reports whether v is written by [Handler.appendPretty] if
+// Options.PrettyValues is set. Values that format themselves, e.g. errors, are
+// not.
+func isPretty(v any) bool {
+	switch v.(type) {
+	case error, fmt.Stringer, fmt.Formatter:
+		return false
+	}
+
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+	switch rv.Kind() {
+	case reflect.Struct, reflect.Map, reflect.Slice, reflect.Array:
+		return true
+	default:
+		return false
+	}
+}
+
+// appendPretty writes v in a compact, colorized Go-literal-like form, e.g.
+//
+//	{Name:"Jane Doe" Tags:[a b] Meta:{k:v}}
+//
+// Structs, maps, slices and arrays nested deeper than Options.MaxValueDepth,
+// and elements beyond Options.MaxValueLength are elided with "…". The style of
+// the value is given by style.
+func (h *Handler) appendPretty(buf *buffer, v reflect.Value, style Style, depth int) {
+	if !v.IsValid() {
+		buf.WriteString("<nil>")
+		return
+	}
+
+	// values that format themselves
+	if depth > 0 && v.CanInterface() {
+		switch cv := v.Interface().(type) {
+		case encoding.TextMarshaler:
+			if data, err := cv.MarshalText(); err == nil {
+				appendString(buf, string(data), true, !h.opts.NoColor)
+				return
+			}
+		case error:
+			appendString(buf, cv.Error(), true, !h.opts.NoColor)
+			return
+		case fmt.Stringer:
+			appendString(buf, cv.String(), true, !h.opts.NoColor)
+			return
+		}
+	}
+
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			buf.WriteString("<nil>")
+			return
+		}
+		h.appendPretty(buf, v.Elem(), style, depth)
+	case reflect.Struct:
+		if depth >= h.opts.MaxValueDepth {
+			buf.WriteString("{…}")
+			return
+		}
+		buf.WriteByte('{')
+		for i := 0; i < v.NumField(); i++ {
+			if !h.appendPrettySep(buf, i) {
+				break
+			}
+			h.appendPrettyKey(buf, v.Type().Field(i).Name, style)
+			h.appendPretty(buf, v.Field(i), style, depth+1)
+		}
+		buf.WriteByte('}')
+	case reflect.Map:
+		if depth >= h.opts.MaxValueDepth {
+			buf.WriteString("{…}")
+			return
+		}
+
+		// sort entries by key
+		type entry struct {
+			key string
+			val reflect.Value
+		}
+		entries := make([]entry, 0, v.Len())
+		for iter := v.MapRange(); iter.Next(); {
+			entries = append(entries, entry{fmt.Sprint(iter.Key()), iter.Value()})
+		}
+		slices.SortFunc(entries, func(a, b entry) int { return cmp.Compare(a.key, b.key) })
+
+		buf.WriteByte('{')
+		for i, e := range entries {
+			if !h.appendPrettySep(buf, i) {
+				break
+			}
+			h.appendPrettyKey(buf, e.key, style)
+			h.appendPretty(buf, e.val, style, depth+1)
+		}
+		buf.WriteByte('}')
+	case reflect.Slice, reflect.Array:
+		if depth >= h.opts.MaxValueDepth {
+			buf.WriteString("[…]")
+			return
+		}
+		buf.WriteByte('[')
+		for i := 0; i < v.Len(); i++ {
+			if !h.appendPrettySep(buf, i) {
+				break
+			}
+			h.appendPretty(buf, v.Index(i), style, depth+1)
+		}
+		buf.WriteByte(']')
+	case reflect.String:
+		appendString(buf, v.String(), true, !h.opts.NoColor)
+	case reflect.Bool:
+		*buf = strconv.AppendBool(*buf, v.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		*buf = strconv.AppendInt(*buf, v.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		*buf = strconv.AppendUint(*buf, v.Uint(), 10)
+	case reflect.Float32, reflect.Float64:
+		*buf = strconv.AppendFloat(*buf, v.Float(), 'g', -1, v.Type().Bits())
+	default:
+		appendString(buf, fmt.Sprint(v), true, !h.opts.NoColor)
+	}
+}
+
+// appendPrettySep writes the separator before the i-th element of a struct,
+// map, slice or array. It reports false and writes "…" if the element exceeds
+// Options.MaxValueLength.
+func (h *Handler) appendPrettySep(buf *buffer, i int) bool {
+	if i > 0 {
+		buf.WriteByte(' ')
+	}
+	if i >= h.opts.MaxValueLength {
+		buf.WriteString("…")
+		return false
+	}
+	return true
+}
+
+// appendPrettyKey writes the key of a struct field or map entry in the
+// Theme.Key style on top of the style of the value.
+func (h *Handler) appendPrettyKey(buf *buffer, key string, style Style) {
+	keyStyle := style.with(h.theme.Key)
+	h.appendStyle(buf, style, keyStyle)
+	appendString(buf, key, true, !h.opts.NoColor)
+	buf.WriteByte(':')
+	h.appendStyle(buf, keyStyle, style)
+}
@@ -0,0 +1,171 @@
+package tint
+
+import (
+	"log/slog"
+	"net/url"
+	"path"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// SourceFormat is the format of the source code location.
+type SourceFormat int
+
+const (
+	SourceFormatShort    SourceFormat = iota // dir/file.go:42
+	SourceFormatLong                         // /home/user/module/pkg/dir/file.go:42
+	SourceFormatRelative                     // pkg/dir/file.go:42
+	SourceFormatBase                         // file.go:42
+)
+
+// SourceLinkFile can be used as Options.SourceLink to link sources to their
+// files.
+const SourceLinkFile = "file://{path}"
+
+// modulePath returns the path of the main module, or "" if the binary was
+// built without module support.
+var modulePath = sync.OnceValue(func() string {
+	if info, ok := debug.ReadBuildInfo(); ok {
+		return info.Main.Path
+	}
+	return ""
+})
+
+// callerFrame returns the frame of the function that logged the record with
+// the given pc. Frames of functions with a prefix in Options.SourceSkip are
+// skipped, unless all frames are.
+func (h *Handler) callerFrame(pc uintptr) runtime.Frame {
+	if len(h.opts.SourceSkip) == 0 {
+		f, _ := runtime.CallersFrames([]uintptr{pc}).Next()
+		return f
+	}
+
+	frames := runtime.CallersFrames(callerStack(pc))
+	first, more := frames.Next()
+	for f := first; ; f, more = frames.Next() {
+		if !h.skipFrame(f.Function) {
+			return f
+		}
+		if !more {
+			return first
+		}
+	}
+}
+
+// skipFrame reports whether the frame of the given function is skipped, based
+// on Options.SourceSkip.
+func (h *Handler) skipFrame(function string) bool {
+	for _, prefix := range h.opts.SourceSkip {
+		if strings.HasPrefix(function, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// appendSource writes the source in the format of Options.SourceFormat,
+// followed by the function name if Options.SourceFunction is set.
+func (h *Handler) appendSource(buf *buffer, src *slog.Source) {
+	h.appendSourceFile(buf, src)
+	if h.opts.SourceFunction && src.Function != "" {
+		buf.WriteByte(' ')
+		buf.WriteString(shortFunction(src.Function))
+	}
+}
+
+// appendSourceFile writes the file and line of the source in the format of
+// Options.SourceFormat, as a hyperlink if Options.SourceLink is set.
+func (h *Handler) appendSourceFile(buf *buffer, src *slog.Source) {
+	if h.opts.SourceLink != "" && !h.opts.NoColor && src.File != "" {
+		buf.WriteString(ansiLinkStart)
+		buf.WriteString(h.sourceLink(src))
+		buf.WriteString(ansiLinkEnd)
+		defer func() {
+			buf.WriteString(ansiLinkStart)
+			buf.WriteString(ansiLinkEnd)
+		}()
+	}
+
+	dir, file := filepath.Split(src.File)
+
+	switch h.opts.SourceFormat {
+	case SourceFormatLong:
+		buf.WriteString(src.File)
+	case SourceFormatRelative:
+		if rel, ok := h.relativeSource(src); ok {
+			buf.WriteString(rel)
+			break
+		}
+		buf.WriteString(filepath.Join(filepath.Base(dir), file))
+	case SourceFormatBase:
+		buf.WriteString(file)
+	default:
+		buf.WriteString(filepath.Join(filepath.Base(dir), file))
+	}
+	buf.WriteByte(':')
+	*buf = strconv.AppendInt(*buf, int64(src.Line), 10)
+}
+
+// sourceLink returns the URL of the source, given by the template in
+// Options.SourceLink.
+func (h *Handler) sourceLink(src *slog.Source) string {
+	path := (&url.URL{Path: filepath.ToSlash(src.File)}).EscapedPath()
+	return strings.NewReplacer(
+		"{path}", path,
+		"{line}", strconv.Itoa(src.Line),
+	).Replace(h.opts.SourceLink)
+}
+
+// relativeSource returns the file path of the source relative to
+// Options.SourceRoot, or relative to the root of its module. For sources
+// outside of the main module, the path is the import path of the package
+// followed by the file name, e.g. "net/http/server.go".
+func (h *Handler) relativeSource(src *slog.Source) (string, bool) {
+	if root := h.opts.SourceRoot; root != "" {
+		rel, ok := strings.CutPrefix(src.File, root)
+		if !ok || (rel != "" && !isPathSeparator(rel[0]) && !isPathSeparator(root[len(root)-1])) {
+			return "", false // not within root
+		}
+		return strings.TrimLeft(rel, `/\`), true
+	}
+
+	pkg := funcPackage(src.Function)
+	if pkg == "" || pkg == "main" {
+		return "", false // directory of the main package is unknown
+	}
+	pkg = strings.TrimSuffix(pkg, "_test") // external test package
+	if mod := modulePath(); mod != "" {
+		if pkg == mod {
+			pkg = ""
+		} else {
+			pkg = strings.TrimPrefix(pkg, mod+"/")
+		}
+	}
+	return path.Join(pkg, filepath.Base(src.File)), true
+}
+
+// isPathSeparator reports whether c separates the elements of a file path.
+func isPathSeparator(c byte) bool {
+	return c == '/' || c == '\\'
+}
+
+// funcPackage returns the import path of the package of the function with the
+// given fully qualified name, e.g. "net/http" for "net/http.(*conn).serve".
+func funcPackage(function string) string {
+	slash := strings.LastIndexByte(function, '/')
+	if i := strings.IndexByte(function[slash+1:], '.'); i >= 0 {
+		return function[:slash+1+i]
+	}
+	return ""
+}
+
+// shortFunction returns the name of the function with the given fully
+// qualified name without the directories of its package path, e.g.
+// "http.(*conn).serve" for "net/http.(*conn).serve".
+func shortFunction(function string) string {
+	return function[strings.LastIndexByte(function, '/')+1:]
+}
@@ -0,0 +1,130 @@
+package tint
+
+import (
+	"log/slog"
+	"reflect"
+	"runtime"
+	"strings"
+)
+
+// maxStackDepth is the maximum number of frames of a stack trace.
+const maxStackDepth = 64
+
+// errorStack returns the program counters of the stack trace of the innermost
+// error in the tree of err that provides one, or nil if none does. Stack traces
+// are provided by a Callers() []uintptr method, or by a StackTrace() method
+// that returns a slice of uintptr-based frames, as in github.com/pkg/errors.
+func errorStack(err error) []uintptr {
+	return errorStackDepth(err, 0)
+}
+
+func errorStackDepth(err error, depth int) []uintptr {
+	if depth >= maxErrorDepth {
+		return nil
+	}
+	for _, err := range unwrapErr(err) {
+		if pcs := errorStackDepth(err, depth+1); pcs != nil {
+			return pcs
+		}
+	}
+
+	if v := reflect.ValueOf(err); v.Kind() == reflect.Pointer && v.IsNil() {
+		return nil
+	}
+	if e, ok := err.(interface{ Callers() []uintptr }); ok {
+		return e.Callers()
+	}
+	m := reflect.ValueOf(err).MethodByName("StackTrace")
+	if !m.IsValid() || m.Type().NumIn() != 0 || m.Type().NumOut() != 1 {
+		return nil
+	}
+	if out := m.Type().Out(0); out.Kind() != reflect.Slice || out.Elem().Kind() != reflect.Uintptr {
+		return nil
+	}
+	frames := m.Call(nil)[0]
+	pcs := make([]uintptr, frames.Len())
+	for i := range pcs {
+		pcs[i] = uintptr(frames.Index(i).Uint())
+	}
+	return pcs
+}
+
+// callerStack returns the program counters of the stack of the goroutine that
+// logged the record with the given pc, starting at pc. If the record was not
+// logged by the calling goroutine, only pc is returned.
+func callerStack(pc uintptr) []uintptr {
+	pcs := make([]uintptr, maxStackDepth)
+	pcs = pcs[:runtime.Callers(2, pcs)]
+	for i, p := range pcs {
+		if p == pc {
+			return pcs[i:]
+		}
+	}
+	return []uintptr{pc}
+}
+
+// appendStack writes the stack trace as a block, with one frame per line.
+// Leading frames of functions with a prefix in Options.SourceSkip are skipped.
+// Frames of the standard library are written in the Theme.Source style.
+func (h *Handler) appendStack(buf *buffer, pcs []uintptr) {
+	if len(pcs) > maxStackDepth {
+		pcs = pcs[:maxStackDepth]
+	}
+
+	var wroteKey bool
+	skip := true // skip leading frames of wrappers, like the source
+	frames := runtime.CallersFrames(pcs)
+	for {
+		f, more := frames.Next()
+		skip = skip && more && h.skipFrame(f.Function)
+		if !skip && f.Function != "" && h.includeFrame(f.Function) {
+			if !wroteKey {
+				h.appendBlockKey(buf, "stack", "", h.theme.Key)
+				wroteKey = true
+			}
+
+			funcStyle, srcStyle := Style{}, h.theme.Source
+			if isStdlib(f.Function) {
+				funcStyle = srcStyle
+			}
+
+			h.appendGutter(buf)
+			h.appendStyle(buf, Style{}, funcStyle)
+			buf.WriteString(shortFunction(f.Function))
+			h.appendStyle(buf, funcStyle, Style{})
+			buf.WriteByte(' ')
+			h.appendStyle(buf, Style{}, srcStyle)
+			h.appendSourceFile(buf, &slog.Source{Function: f.Function, File: f.File, Line: f.Line})
+			h.appendStyle(buf, srcStyle, Style{})
+			buf.WriteByte('\n')
+		}
+		if !more {
+			break
+		}
+	}
+}
+
+// includeFrame reports whether a frame of the given function is written, based
+// on Options.StackTracePrefixes.
+func (h *Handler) includeFrame(function string) bool {
+	if len(h.opts.StackTracePrefixes) == 0 {
+		return true
+	}
+	for _, prefix := range h.opts.StackTracePrefixes {
+		if strings.HasPrefix(function, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// isStdlib reports whether the function with the given fully qualified name is
+// part of the standard library, i.e. the first element of its package path
+// contains no dot.
+func isStdlib(function string) bool {
+	elem, _, _ := strings.Cut(function, "/")
+	if !strings.Contains(function, "/") {
+		elem, _, _ = strings.Cut(function, ".")
+	}
+	return elem != "main" && !strings.Contains(elem, ".")
+}
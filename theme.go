@@ -0,0 +1,164 @@
+package tint
+
+import (
+	"log/slog"
+)
+
+// Style defines how a part of a log record is written. The zero Style writes
+// the text unstyled.
+type Style struct {
+	Foreground    Color // Text color
+	Background    Color // Background color
+	Bold          bool  // Increased intensity
+	Faint         bool  // Decreased intensity
+	Italic        bool  // Italic text
+	Underline     bool  // Underlined text
+	Inverse       bool  // Swapped foreground and background colors
+	Strikethrough bool  // Crossed-out text
+}
+
+// with returns s with the set attributes of o applied on top.
+func (s Style) with(o Style) Style {
+	if o.Foreground != 0 {
+		s.Foreground = o.Foreground
+	}
+	if o.Background != 0 {
+		s.Background = o.Background
+	}
+	s.Bold = s.Bold || o.Bold
+	s.Faint = s.Faint || o.Faint
+	s.Italic = s.Italic || o.Italic
+	s.Underline = s.Underline || o.Underline
+	s.Inverse = s.Inverse || o.Inverse
+	s.Strikethrough = s.Strikethrough || o.Strikethrough
+	return s
+}
+
+// Theme defines the [Style] of each part of a log record.
+type Theme struct {
+	Time    Style // Style of the time
+	Debug   Style // Style of the level DBG
+	Info    Style // Style of the level INF
+	Warn    Style // Style of the level WRN
+	Error   Style // Style of the level ERR
+	Source  Style // Style of the source code location
+	Message Style // Style of the message
+	Key     Style // Style of attribute keys, including the "=" separator
+	Value   Style // Style of attribute values
+	Group   Style // Style of the group prefix of attribute keys
+	Err     Style // Style of attributes created with [Err]
+	Gutter  Style // Style of the gutter of blocks written below the line
+
+	JSONKey     Style // Style of object keys in JSON values
+	JSONString  Style // Style of strings in JSON values
+	JSONNumber  Style // Style of numbers in JSON values
+	JSONLiteral Style // Style of true, false and null in JSON values
+}
+
+// DarkTheme returns the default theme for terminals with a dark background.
+func DarkTheme() *Theme {
+	return &Theme{
+		Time:   Style{Faint: true},
+		Info:   Style{Foreground: ANSIColor(10)},
+		Warn:   Style{Foreground: ANSIColor(11)},
+		Error:  Style{Foreground: ANSIColor(9)},
+		Source: Style{Faint: true},
+		Key:    Style{Faint: true},
+		Group:  Style{Faint: true},
+		Err:    Style{Foreground: ANSIColor(9)},
+		Gutter: Style{Faint: true},
+
+		JSONKey:     Style{Foreground: ANSIColor(12)},
+		JSONString:  Style{Foreground: ANSIColor(2)},
+		JSONNumber:  Style{Foreground: ANSIColor(6)},
+		JSONLiteral: Style{Foreground: ANSIColor(5)},
+	}
+}
+
+// LightTheme returns a theme for terminals with a light background. It avoids
+// faint text, which is hard to read on light backgrounds.
+func LightTheme() *Theme {
+	return &Theme{
+		Time:   Style{Foreground: ANSIColor(240)},
+		Debug:  Style{Foreground: ANSIColor(5)},
+		Info:   Style{Foreground: ANSIColor(2)},
+		Warn:   Style{Foreground: ANSIColor(3)},
+		Error:  Style{Foreground: ANSIColor(1)},
+		Source: Style{Foreground: ANSIColor(240)},
+		Key:    Style{Foreground: ANSIColor(4)},
+		Group:  Style{Foreground: ANSIColor(4)},
+		Err:    Style{Foreground: ANSIColor(1)},
+		Gutter: Style{Foreground: ANSIColor(240)},
+
+		JSONKey:     Style{Foreground: ANSIColor(4)},
+		JSONString:  Style{Foreground: ANSIColor(2)},
+		JSONNumber:  Style{Foreground: ANSIColor(6)},
+		JSONLiteral: Style{Foreground: ANSIColor(5)},
+	}
+}
+
+// level returns the style of the given level.
+func (t *Theme) level(level slog.Level) Style {
+	switch {
+	case level < slog.LevelInfo:
+		return t.Debug
+	case level < slog.LevelWarn:
+		return t.Info
+	case level < slog.LevelError:
+		return t.Warn
+	default:
+		return t.Error
+	}
+}
+
+// appendStyle writes the ANSI escape sequence that switches from style from to
+// style to. Switching to the zero Style resets all attributes.
+func appendStyle(buf *buffer, from, to Style) {
+	if from == to {
+		return
+	}
+	if to == (Style{}) {
+		buf.WriteString(ansiReset)
+		return
+	}
+
+	buf.WriteString("\u001b[")
+	n := len(*buf)
+	sep := func() {
+		if len(*buf) > n {
+			buf.WriteByte(';')
+		}
+	}
+
+	attr := func(from, to bool, set, unset string) {
+		if to && !from {
+			sep()
+			buf.WriteString(set)
+		} else if !to && from {
+			sep()
+			buf.WriteString(unset)
+		}
+	}
+
+	// bold and faint are both reset by "22"
+	if (from.Bold && !to.Bold) || (from.Faint && !to.Faint) {
+		sep()
+		buf.WriteString("22")
+		from.Bold, from.Faint = false, false
+	}
+	attr(from.Bold, to.Bold, "1", "22")
+	attr(from.Faint, to.Faint, "2", "22")
+	attr(from.Italic, to.Italic, "3", "23")
+	attr(from.Underline, to.Underline, "4", "24")
+	attr(from.Inverse, to.Inverse, "7", "27")
+	attr(from.Strikethrough, to.Strikethrough, "9", "29")
+	if to.Foreground != from.Foreground {
+		sep()
+		appendColor(buf, to.Foreground, false)
+	}
+	if to.Background != from.Background {
+		sep()
+		appendColor(buf, to.Background, true)
+	}
+	buf.WriteByte('m')
+}
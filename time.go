@@ -0,0 +1,206 @@
+package tint
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// TimeMode determines how the time of a record is written.
+type TimeMode int
+
+const (
+	TimeModeWall      TimeMode = iota // wall clock in Options.TimeFormat, e.g. "Jan 2 15:04:05.000"
+	TimeModeElapsed                   // elapsed since the handler was created, e.g. "+1.234s"
+	TimeModeDelta                     // elapsed since the previous record, e.g. "Δ12ms"
+	TimeModeWallDelta                 // wall clock and delta, e.g. "Jan 2 15:04:05.000 Δ12ms"
+)
+
+// clock measures the time elapsed since the creation of a handler and since
+// the previous record.
+type clock struct {
+	start time.Time
+	prev  atomic.Int64 // elapsed time of the previous record
+}
+
+func newClock() *clock {
+	return &clock{start: time.Now()}
+}
+
+// elapsed returns the time elapsed between the creation of the handler and t.
+func (c *clock) elapsed(t time.Time) time.Duration {
+	return t.Sub(c.start)
+}
+
+// delta returns the time elapsed between the previous record and t, and
+// records t as the time of the previous record. The delta of the first record
+// is the time elapsed since the creation of the handler. Records that are
+// handled out of order have a delta of 0.
+func (c *clock) delta(t time.Time) time.Duration {
+	elapsed := c.elapsed(t)
+	prev := time.Duration(c.prev.Swap(int64(elapsed)))
+	return max(elapsed-prev, 0)
+}
+
+// inLocation returns t in Options.TimeLocation, or t if it is nil.
+func (h *Handler) inLocation(t time.Time) time.Time {
+	if h.opts.TimeLocation == nil {
+		return t
+	}
+	return t.In(h.opts.TimeLocation)
+}
+
+// appendTimeMode writes the time t in the format of Options.TimeMode.
+func (h *Handler) appendTimeMode(buf *buffer, t time.Time) {
+	t = h.inLocation(t)
+	switch h.opts.TimeMode {
+	case TimeModeElapsed:
+		elapsed := h.clock.elapsed(t).Truncate(time.Millisecond)
+		buf.WriteByte('+')
+		*buf = strconv.AppendFloat(*buf, elapsed.Seconds(), 'f', 3, 64)
+		buf.WriteByte('s')
+	case TimeModeDelta:
+		appendDelta(buf, h.clock.delta(t))
+	case TimeModeWallDelta:
+		*buf = t.AppendFormat(*buf, h.opts.TimeFormat)
+		buf.WriteByte(' ')
+		appendDelta(buf, h.clock.delta(t))
+	default:
+		*buf = t.AppendFormat(*buf, h.opts.TimeFormat)
+	}
+}
+
+// appendDelta writes the delta d truncated to milliseconds, or to microseconds
+// if it is shorter than a millisecond, e.g. "Δ12ms".
+func appendDelta(buf *buffer, d time.Duration) {
+	if d >= time.Millisecond {
+		d = d.Truncate(time.Millisecond)
+	} else {
+		d = d.Truncate(time.Microsecond)
+	}
+	buf.WriteString("Δ")
+	buf.WriteString(d.String())
+}
+
+// DurationFormat is the format of duration attributes.
+type DurationFormat int
+
+const (
+	DurationFormatString  DurationFormat = iota // time.Duration.String, e.g. "1.234567s"
+	DurationFormatHuman                         // rounded to one decimal below 10, e.g. "1.2s" or "350ms"
+	DurationFormatMillis                        // milliseconds, e.g. "1234.567ms"
+	DurationFormatSeconds                       // seconds, e.g. "1.234567s"
+)
+
+// DurationStyle defines the style of durations at or above a threshold.
+type DurationStyle struct {
+	// Minimum duration
+	Duration time.Duration
+
+	// Style of the duration
+	Style Style
+}
+
+// appendDuration writes the duration d in the format of
+// Options.DurationFormat and in the style of Options.DurationStyles on top of
+// style.
+func (h *Handler) appendDuration(buf *buffer, d time.Duration, style Style) {
+	// find the style with the highest threshold <= d
+	durStyle, threshold := style, time.Duration(math.MinInt64)
+	for _, s := range h.opts.DurationStyles {
+		if d >= s.Duration && s.Duration >= threshold {
+			durStyle, threshold = h.opts.ColorProfile.convertStyle(s.Style).with(style), s.Duration
+		}
+	}
+	h.appendStyle(buf, style, durStyle)
+	appendDurationFormat(buf, d, h.opts.DurationFormat)
+	h.appendStyle(buf, durStyle, style)
+}
+
+// appendDurationFormat writes the duration d in the given format.
+func appendDurationFormat(buf *buffer, d time.Duration, format DurationFormat) {
+	switch format {
+	case DurationFormatHuman:
+		appendHumanDuration(buf, d)
+	case DurationFormatMillis:
+		*buf = strconv.AppendFloat(*buf, float64(d)/float64(time.Millisecond), 'f', -1, 64)
+		buf.WriteString("ms")
+	case DurationFormatSeconds:
+		*buf = strconv.AppendFloat(*buf, d.Seconds(), 'f', -1, 64)
+		buf.WriteByte('s')
+	default:
+		buf.WriteString(d.String())
+	}
+}
+
+// humanUnits are the units of durations written by appendHumanDuration that
+// are shorter than a minute.
+var humanUnits = []struct {
+	name  string
+	scale time.Duration
+}{
+	{"ns", time.Nanosecond},
+	{"µs", time.Microsecond},
+	{"ms", time.Millisecond},
+	{"s", time.Second},
+}
+
+// appendHumanDuration writes the duration d in the largest unit in which it is
+// at least 1, rounded to one decimal below 10 and to whole units otherwise,
+// e.g. "1.2s" or "350ms". Durations of a minute or more are rounded to
+// seconds, or to minutes if they are an hour or more, e.g. "2m5s" or "1h30m".
+func appendHumanDuration(buf *buffer, d time.Duration) {
+	if d < 0 {
+		buf.WriteByte('-')
+		if d == math.MinInt64 {
+			d = math.MaxInt64 // -math.MinInt64 overflows
+		} else {
+			d = -d
+		}
+	}
+
+	for i, unit := range humanUnits {
+		prec, step := 1, unit.scale/10
+		if unit.scale == time.Nanosecond || d >= 10*unit.scale {
+			prec, step = 0, unit.scale
+		}
+
+		// round before choosing the unit, so that e.g. 999.96ms is written as
+		// "1s" instead of "1000ms"
+		r := roundDuration(d, step)
+		if i+1 < len(humanUnits) && r >= 1000*unit.scale || i+1 == len(humanUnits) && r >= time.Minute {
+			continue
+		}
+
+		*buf = strconv.AppendFloat(*buf, float64(r)/float64(unit.scale), 'f', prec, 64)
+		if n := len(*buf); prec > 0 && (*buf)[n-1] == '0' {
+			*buf = (*buf)[:n-2] // trim ".0"
+		}
+		buf.WriteString(unit.name)
+		return
+	}
+
+	r := roundDuration(d, time.Second)
+	if r >= time.Hour {
+		r = roundDuration(d, time.Minute)
+	}
+	str := r.String()
+	if strings.HasSuffix(str, "m0s") {
+		str = str[:len(str)-2] // trim "0s"
+	}
+	if strings.HasSuffix(str, "h0m") {
+		str = str[:len(str)-2] // trim "0m"
+	}
+	buf.WriteString(str)
+}
+
+// roundDuration returns d rounded to a multiple of m, or truncated if rounding
+// overflows.
+func roundDuration(d, m time.Duration) time.Duration {
+	if r := d.Round(m); r%m == 0 {
+		return r
+	}
+	return d.Truncate(m)
+}